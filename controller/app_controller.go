@@ -54,6 +54,74 @@ func (c *appController) Update(ctx *gin.Context) {
 	}
 }
 
+func (c *appController) RevealSecret(ctx *gin.Context) {
+	m := &object.AppSecretRevealParam{}
+
+	if id, err := strconv.ParseInt(ctx.Param("id"), 10, 64); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else if err := ctx.ShouldBind(&m); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else {
+		m.Id = id
+		m.Token = ctx.GetHeader("X-Reveal-Token")
+		m.ClientIp = ctx.ClientIP()
+
+		if appSecret, err := object.RevealAppSecret(*m); err != nil {
+			winter.RenderInternalServerErrorResult(ctx, err)
+		} else {
+			winter.RenderSuccessResult(ctx, appSecret)
+		}
+	}
+}
+
+func (c *appController) SetSecondaryCredential(ctx *gin.Context) {
+	m := &object.AppCredentialParam{}
+
+	if id, err := strconv.ParseInt(ctx.Param("id"), 10, 64); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else if err := ctx.ShouldBind(&m); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else {
+		m.Id = id
+
+		if app, err := object.SetAppSecondaryCredential(*m); err != nil {
+			winter.RenderInternalServerErrorResult(ctx, err)
+		} else {
+			winter.RenderSuccessResult(ctx, app)
+		}
+	}
+}
+
+func (c *appController) CutoverCredential(ctx *gin.Context) {
+	if id, err := strconv.ParseInt(ctx.Param("id"), 10, 64); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else if app, err := object.CutoverAppCredential(id); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, app)
+	}
+}
+
+func (c *appController) DeleteSecondaryCredential(ctx *gin.Context) {
+	if id, err := strconv.ParseInt(ctx.Param("id"), 10, 64); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else if app, err := object.DeleteAppSecondaryCredential(id); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, app)
+	}
+}
+
+func (c *appController) Test(ctx *gin.Context) {
+	if id, err := strconv.ParseInt(ctx.Param("id"), 10, 64); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else if report, err := object.TestApp(id); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, report)
+	}
+}
+
 func (c *appController) Delete(ctx *gin.Context) {
 	if id, err := strconv.ParseInt(ctx.Param("id"), 10, 64); err != nil {
 		winter.RenderBadRequestResult(ctx, err)
@@ -54,6 +54,16 @@ func (c *bucketController) Update(ctx *gin.Context) {
 	}
 }
 
+func (c *bucketController) Test(ctx *gin.Context) {
+	if id, err := strconv.ParseInt(ctx.Param("id"), 10, 64); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else if report, err := object.TestBucket(id); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, report)
+	}
+}
+
 func (c *bucketController) Delete(ctx *gin.Context) {
 	if id, err := strconv.ParseInt(ctx.Param("id"), 10, 64); err != nil {
 		winter.RenderBadRequestResult(ctx, err)
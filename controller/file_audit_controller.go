@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/easynet-cn/file-service/log"
+	"github.com/easynet-cn/file-service/object"
+	"github.com/easynet-cn/winter"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type fileAuditController struct{}
+
+var FileAuditController = &fileAuditController{}
+
+func (c *fileAuditController) Start(ctx *gin.Context) {
+	m := &object.FileAuditParam{}
+
+	if err := ctx.ShouldBind(&m); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else if auditEntity, err := object.StartFileAudit(*m); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, auditEntity)
+	}
+}
+
+func (c *fileAuditController) SearchPage(ctx *gin.Context) {
+	searchParam := &winter.PageParam{}
+
+	if err := ctx.ShouldBind(&searchParam); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else if pageResult, err := object.SearchPageFileAudits(*searchParam); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, pageResult)
+	}
+}
+
+func (c *fileAuditController) Get(ctx *gin.Context) {
+	if id, err := strconv.ParseInt(ctx.Param("id"), 10, 64); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else if auditEntity, err := object.GetFileAudit(id); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, auditEntity)
+	}
+}
+
+func (c *fileAuditController) SearchIssuePage(ctx *gin.Context) {
+	searchParam := &object.FileAuditIssuePageParam{}
+
+	if id, err := strconv.ParseInt(ctx.Param("id"), 10, 64); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else if err := ctx.ShouldBind(&searchParam); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else {
+		searchParam.AuditId = id
+
+		if pageResult, err := object.SearchPageFileAuditIssues(*searchParam); err != nil {
+			winter.RenderInternalServerErrorResult(ctx, err)
+		} else {
+			winter.RenderSuccessResult(ctx, pageResult)
+		}
+	}
+}
+
+func (c *fileAuditController) ExportIssues(ctx *gin.Context) {
+	if id, err := strconv.ParseInt(ctx.Param("id"), 10, 64); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else if auditEntity, err := object.GetFileAudit(id); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		ctx.Header("Content-Type", "text/csv; charset=utf-8")
+		ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="file-audit-%d.csv"`, auditEntity.Id))
+		ctx.Status(http.StatusOK)
+
+		if err := object.ExportFileAuditIssues(auditEntity.Id, ctx.Writer); err != nil {
+			log.Logger.Error("导出巡检异常失败", zap.Int64("auditId", auditEntity.Id), zap.Error(err))
+		}
+	}
+}
@@ -4,20 +4,29 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
-	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/easynet-cn/file-service/log"
 	"github.com/easynet-cn/file-service/object"
 	"github.com/easynet-cn/winter"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
+	"github.com/gin-gonic/gin/binding"
 	"go.uber.org/zap"
 )
 
+const (
+	maxUploadFormValueSize  = 1024 * 1024
+	maxUploadBase64JsonSize = 32 * 1024 * 1024
+)
+
 type fileController struct{}
 
 var FileController = &fileController{}
@@ -58,97 +67,316 @@ func (c *fileController) GetUploadToken(ctx *gin.Context) {
 	}
 }
 
-func (c *fileController) Upload(ctx *gin.Context) {
-	if file, err := ctx.FormFile("file"); err != nil {
+func (c *fileController) InstantUpload(ctx *gin.Context) {
+	m := &object.OssUploadFile{}
+
+	if err := ctx.ShouldBind(&m); err != nil {
 		winter.RenderBadRequestResult(ctx, err)
-	} else if file == nil {
-		winter.RenderBadRequestResult(ctx, errors.New("上传文件不能为空"))
+	} else if result, err := object.InstantUpload(*m); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
 	} else {
-		fileExt := strings.TrimPrefix(path.Ext(file.Filename), ".")
-		tempFile := path.Join(os.TempDir(), uuid.NewString()+path.Ext(file.Filename))
+		winter.RenderSuccessResult(ctx, result)
+	}
+}
+
+// 逐个读取multipart分段，查询字符串和文件之前的表单字段齐全时文件内容直接流式写入存储，此时文件之后不能再有表单字段；
+// 文件位于bucket字段之前时先暂存到临时文件，读取完全部表单字段后再上传，暂存大小受限
+func (c *fileController) Upload(ctx *gin.Context) {
+	reader, err := ctx.Request.MultipartReader()
 
-		if err := ctx.SaveUploadedFile(file, tempFile); err != nil {
-			log.Logger.Error("保存上传文件失败", zap.Any("file", file), zap.Any("tempFile", tempFile), zap.Error(err))
+	if err != nil {
+		winter.RenderBadRequestResult(ctx, err)
 
-			winter.RenderInternalServerErrorResult(ctx, err)
-		} else {
-			m := &object.OssUploadFile{}
+		return
+	}
+
+	form := ctx.Request.URL.Query()
 
-			if err := ctx.ShouldBind(&m); err != nil {
+	var file *os.File
+	var filename string
+
+	defer func() {
+		if file != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+
+	for {
+		part, err := reader.NextPart()
+
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			winter.RenderBadRequestResult(ctx, err)
+
+			return
+		}
+
+		if part.FormName() == "" {
+			continue
+		} else if part.FileName() == "" {
+			if value, err := readUploadFormValue(part); err != nil {
 				winter.RenderBadRequestResult(ctx, err)
+
+				return
 			} else {
-				m.SourceFile = file.Filename
-				m.SourceFileSize = file.Size
-				m.SourceFileType = fileExt
-
-				if m.ProcessParamsStr != "" {
-					processParams := make([]object.ProcessParam, 0)
-
-					if err := json.Unmarshal([]byte(m.ProcessParamsStr), &processParams); err != nil {
-						log.Logger.Error("解析ProcessParamsStr失败", zap.String("ProcessParamsStr", m.ProcessParamsStr), zap.Error(err))
-					} else {
-						m.ProcessParams = processParams
-					}
-				}
-
-				if file, err := object.UploadFile(*m, tempFile); err != nil {
-					log.Logger.Error("上传文件失败", zap.Any("uploadFile", m), zap.String("tempFile", tempFile), zap.Error(err))
-
-					winter.RenderInternalServerErrorResult(ctx, err)
-				} else {
-					winter.RenderSuccessResult(ctx, file)
-				}
+				form.Add(part.FormName(), value)
 			}
+
+			continue
+		} else if part.FormName() != "file" || file != nil {
+			continue
+		}
+
+		if form.Get("bucket") != "" {
+			c.uploadFile(ctx, form, part.FileName(), &uploadFilePartReader{part: part, reader: reader})
+
+			return
 		}
+
+		maxSpoolSize := object.GetUploadSpoolMaxSize()
+
+		if file, err = os.CreateTemp("", "file-service-upload-*"); err != nil {
+			winter.RenderInternalServerErrorResult(ctx, err)
+
+			return
+		} else if n, err := io.CopyN(file, part, maxSpoolSize+1); err != nil && !errors.Is(err, io.EOF) {
+			winter.RenderBadRequestResult(ctx, err)
+
+			return
+		} else if n > maxSpoolSize {
+			winter.RenderBadRequestResult(ctx, fmt.Errorf("文件位于bucket字段之前时不能超过%d字节，请将表单字段放在文件之前或通过查询字符串传递bucket", maxSpoolSize))
+
+			return
+		} else if _, err := file.Seek(0, io.SeekStart); err != nil {
+			winter.RenderInternalServerErrorResult(ctx, err)
+
+			return
+		}
+
+		filename = part.FileName()
+	}
+
+	if file == nil {
+		winter.RenderBadRequestResult(ctx, errors.New("上传文件不能为空"))
+	} else {
+		c.uploadFile(ctx, form, filename, file)
 	}
 }
 
+func (c *fileController) uploadFile(ctx *gin.Context, form url.Values, filename string, reader io.Reader) {
+	m := &object.OssUploadFile{}
+
+	if err := binding.MapFormWithTag(m, form, "form"); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+
+		return
+	}
+
+	m.SourceFile = filename
+	m.SourceFileType = strings.TrimPrefix(path.Ext(filename), ".")
+
+	parseProcessParams(m)
+
+	if file, err := object.UploadFile(*m, reader); err != nil {
+		log.Logger.Error("上传文件失败", zap.Any("uploadFile", m), zap.Error(err))
+
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, file)
+	}
+}
+
+// 请求体为JSON时整体解析，大小受限；其他内容类型的请求体为Base64数据，参数通过查询字符串传递，数据流式解码写入存储
 func (c *fileController) UploadBase64(ctx *gin.Context) {
 	m := &object.OssUploadBase64{}
 
-	if err := ctx.BindJSON(&m); err != nil {
+	var reader io.Reader
+
+	if ctx.ContentType() == binding.MIMEJSON {
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxUploadBase64JsonSize)
+
+		if err := ctx.ShouldBindJSON(&m); err != nil {
+			var maxBytesError *http.MaxBytesError
+
+			if errors.As(err, &maxBytesError) {
+				err = fmt.Errorf("JSON请求体不能超过%d字节，较大的文件请直接以Base64数据作为请求体上传", maxUploadBase64JsonSize)
+			}
+
+			winter.RenderBadRequestResult(ctx, err)
+
+			return
+		} else if m.Data == "" {
+			winter.RenderBadRequestResult(ctx, errors.New("上传文件不能为空"))
+
+			return
+		}
+
+		reader = strings.NewReader(m.Data)
+		m.Data = ""
+	} else if err := ctx.ShouldBindQuery(&m.OssUploadFile); err != nil {
 		winter.RenderBadRequestResult(ctx, err)
-	} else if m.Data == "" {
+
+		return
+	} else if ctx.Request.ContentLength == 0 {
 		winter.RenderBadRequestResult(ctx, errors.New("上传文件不能为空"))
-	} else if bytes, err := base64.StdEncoding.DecodeString(m.Data); err != nil {
+
+		return
+	} else {
+		reader = ctx.Request.Body
+	}
+
+	if m.SourceFileType == "" {
+		m.SourceFileType = strings.TrimPrefix(path.Ext(m.SourceFile), ".")
+	}
+
+	parseProcessParams(&m.OssUploadFile)
+
+	if file, err := object.UploadFile(m.OssUploadFile, base64.NewDecoder(base64.StdEncoding, reader)); err != nil {
+		log.Logger.Error("上传文件失败", zap.Any("uploadFile", m), zap.Error(err))
+
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, file)
+	}
+}
+
+func (c *fileController) UploadFromUrl(ctx *gin.Context) {
+	m := &object.UploadUrlParam{}
+
+	if err := ctx.ShouldBind(&m); err != nil {
 		winter.RenderBadRequestResult(ctx, err)
+	} else if file, err := object.UploadFileFromUrl(*m); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
 	} else {
-		fileExt := strings.TrimPrefix(path.Ext(m.SourceFile), ".")
-		tempFile := path.Join(os.TempDir(), uuid.NewString()+path.Ext(m.SourceFile))
+		winter.RenderSuccessResult(ctx, file)
+	}
+}
 
-		if err = os.MkdirAll(filepath.Dir(tempFile), 0750); err != nil {
-			log.Logger.Error("创建临时文件夹失败", zap.Any("file", m), zap.Any("tempFile", tempFile), zap.Error(err))
+func (c *fileController) Delete(ctx *gin.Context) {
+	if id, err := strconv.ParseInt(ctx.Param("id"), 10, 64); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else if result, err := object.DeleteFileById(id); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, result)
+	}
+}
 
-			winter.RenderInternalServerErrorResult(ctx, err)
+func (c *fileController) DeleteBatch(ctx *gin.Context) {
+	deleteParam := &object.DeleteFileParam{}
+
+	if err := ctx.ShouldBindJSON(&deleteParam); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else if len(deleteParam.FileKeys) > 0 && deleteParam.Bucket == "" {
+		winter.RenderBadRequestResult(ctx, errors.New("按文件key删除时bucket不能为空"))
+	} else if results, err := object.DeleteFiles(*deleteParam); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, results)
+	}
+}
+
+func (c *fileController) TrashSearchPage(ctx *gin.Context) {
+	searchParam := &object.SearchFilePageParam{}
+
+	if err := ctx.ShouldBind(&searchParam); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else if pageResult, err := object.SearchPageTrashFiles(*searchParam); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, pageResult)
+	}
+}
+
+func (c *fileController) Restore(ctx *gin.Context) {
+	if id, err := strconv.ParseInt(ctx.Param("id"), 10, 64); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else if file, err := object.RestoreFileById(id); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, file)
+	}
+}
+
+func (c *fileController) Confirm(ctx *gin.Context) {
+	m := &object.ConfirmFileParam{}
+
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&m); err != nil {
+			winter.RenderBadRequestResult(ctx, err)
+
+			return
 		}
+	}
 
-		if err := os.WriteFile(tempFile, bytes, 0666); err != nil {
-			log.Logger.Error("保存上传文件失败", zap.Any("file", m), zap.Any("tempFile", tempFile), zap.Error(err))
+	if id, err := strconv.ParseInt(ctx.Param("id"), 10, 64); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else if file, err := object.ConfirmFileById(id, *m); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, file)
+	}
+}
 
-			winter.RenderInternalServerErrorResult(ctx, err)
-		} else {
-			if m.SourceFileType == "" {
-				m.SourceFileType = fileExt
-			}
+func (c *fileController) LocalDownload(ctx *gin.Context) {
+	bucket := ctx.Param("bucket")
+	fileKey := strings.TrimPrefix(ctx.Param("key"), "/")
 
-			if m.ProcessParamsStr != "" {
-				processParams := make([]object.ProcessParam, 0)
+	if reader, objectInfo, err := object.GetLocalFile(bucket, fileKey, ctx.Query("Expires"), ctx.Query("Signature")); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		defer reader.Close()
 
-				if err := json.Unmarshal([]byte(m.ProcessParamsStr), &processParams); err != nil {
-					log.Logger.Error("解析ProcessParamsStr失败", zap.String("ProcessParamsStr", m.ProcessParamsStr), zap.Error(err))
-				} else {
-					m.ProcessParams = processParams
-				}
-			}
+		contentType, headers := object.GetLocalFileHeaders(fileKey, objectInfo.ContentType)
 
-			if file, err := object.UploadFile(m.OssUploadFile, tempFile); err != nil {
-				log.Logger.Error("上传文件失败", zap.Any("uploadFile", m), zap.String("tempFile", tempFile), zap.Error(err))
+		ctx.DataFromReader(http.StatusOK, objectInfo.Size, contentType, reader, headers)
+	}
+}
 
-				winter.RenderInternalServerErrorResult(ctx, err)
-			} else {
-				winter.RenderSuccessResult(ctx, file)
+func (c *fileController) LocalUpload(ctx *gin.Context) {
+	if file, err := ctx.FormFile("file"); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else if reader, err := file.Open(); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		defer reader.Close()
+
+		fields := make(map[string]string)
+
+		for k, v := range ctx.Request.MultipartForm.Value {
+			if len(v) > 0 {
+				fields[k] = v[0]
 			}
 		}
+
+		if err := object.PutLocalFile(ctx.Param("bucket"), ctx.PostForm("key"), fields, file.Size, ctx.PostForm("policy"), ctx.PostForm("signature"), reader); err != nil {
+			winter.RenderInternalServerErrorResult(ctx, err)
+		} else if status, err := strconv.Atoi(ctx.PostForm("success_action_status")); err == nil && (status == http.StatusOK || status == http.StatusCreated) {
+			ctx.Status(status)
+		} else {
+			ctx.Status(http.StatusNoContent)
+		}
+	}
+}
+
+func (c *fileController) LocalPut(ctx *gin.Context) {
+	if ctx.Query("uploadId") == "" && ctx.Query("partNumber") == "" {
+		if err := object.PutLocalFileByURL(ctx.Param("bucket"), strings.TrimPrefix(ctx.Param("key"), "/"), ctx.Request.URL.Query(), ctx.Request.Header, ctx.Request.Body); err != nil {
+			winter.RenderInternalServerErrorResult(ctx, err)
+		} else {
+			ctx.Status(http.StatusOK)
+		}
+	} else if ctx.Query("uploadId") == "" || ctx.Query("partNumber") == "" {
+		winter.RenderBadRequestResult(ctx, errors.New("缺少uploadId或partNumber"))
+	} else if ctx.Request.ContentLength < 0 {
+		winter.RenderErrorResult(ctx, http.StatusLengthRequired, errors.New("分片上传必须指定Content-Length"))
+	} else if part, err := object.PutLocalFilePart(ctx.Param("bucket"), strings.TrimPrefix(ctx.Param("key"), "/"), ctx.Query("uploadId"), ctx.Query("partNumber"), ctx.Query("Expires"), ctx.Query("Signature"), ctx.Request.Body, ctx.Request.ContentLength); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		ctx.Header("ETag", `"`+part.ETag+`"`)
+		ctx.Status(http.StatusOK)
 	}
 }
 
@@ -175,3 +403,82 @@ func (c *fileController) CreateBatch(ctx *gin.Context) {
 		winter.RenderSuccessResult(ctx, winter.NewRestResult(http.StatusOK, "200", count, ""))
 	}
 }
+
+func (c *fileController) OssCallback(ctx *gin.Context) {
+	if body, err := io.ReadAll(ctx.Request.Body); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else if file, err := object.HandleOssCallback(object.OssCallbackRequest{
+		PubKeyUrl:     ctx.GetHeader("x-oss-pub-key-url"),
+		Authorization: ctx.GetHeader("authorization"),
+		Path:          ctx.Request.URL.Path,
+		RawQuery:      ctx.Request.URL.RawQuery,
+		Body:          body,
+	}); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, file)
+	}
+}
+
+func (c *fileController) GetUploadCredentials(ctx *gin.Context) {
+	m := &object.OssUploadFile{}
+
+	if err := ctx.ShouldBind(&m); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else if credentials, err := object.GetUploadCredentials(*m); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, credentials)
+	}
+}
+
+// 文件读取完毕后检查剩余分段，流式上传时文件之后的表单字段已无法生效，返回错误以中止上传
+type uploadFilePartReader struct {
+	part    *multipart.Part
+	reader  *multipart.Reader
+	checked bool
+}
+
+func (r *uploadFilePartReader) Read(p []byte) (int, error) {
+	n, err := r.part.Read(p)
+
+	if errors.Is(err, io.EOF) && !r.checked {
+		r.checked = true
+
+		for {
+			part, err := r.reader.NextPart()
+
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return n, err
+			} else if part.FormName() != "" && part.FileName() == "" {
+				return n, winter.NewBadRequestBusinessError(fmt.Sprintf("表单字段%s必须位于文件之前或通过查询字符串传递", part.FormName()))
+			}
+		}
+	}
+
+	return n, err
+}
+
+func readUploadFormValue(part *multipart.Part) (string, error) {
+	if bytes, err := io.ReadAll(io.LimitReader(part, maxUploadFormValueSize+1)); err != nil {
+		return "", err
+	} else if len(bytes) > maxUploadFormValueSize {
+		return "", fmt.Errorf("表单字段%s过长", part.FormName())
+	} else {
+		return string(bytes), nil
+	}
+}
+
+func parseProcessParams(m *object.OssUploadFile) {
+	if m.ProcessParamsStr != "" {
+		processParams := make([]object.ProcessParam, 0)
+
+		if err := json.Unmarshal([]byte(m.ProcessParamsStr), &processParams); err != nil {
+			log.Logger.Error("解析ProcessParamsStr失败", zap.String("ProcessParamsStr", m.ProcessParamsStr), zap.Error(err))
+		} else {
+			m.ProcessParams = processParams
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dromara/carbon/v2"
+	"github.com/easynet-cn/file-service/object"
+	"github.com/easynet-cn/winter"
+	"github.com/gin-gonic/gin"
+)
+
+type tusController struct{}
+
+var TusController = &tusController{}
+
+func (c *tusController) Options(ctx *gin.Context) {
+	ctx.Header("Tus-Resumable", object.TusVersion)
+	ctx.Header("Tus-Version", object.TusVersion)
+	ctx.Header("Tus-Extension", object.TusExtension)
+	ctx.Header("Tus-Max-Size", strconv.FormatInt(object.TusMaxSize, 10))
+	ctx.Status(http.StatusNoContent)
+}
+
+func (c *tusController) Create(ctx *gin.Context) {
+	if !c.checkResumable(ctx) {
+		return
+	}
+
+	if ctx.GetHeader("Upload-Defer-Length") != "" {
+		c.renderError(ctx, http.StatusBadRequest, errors.New("不支持Upload-Defer-Length"))
+	} else if uploadLength, err := strconv.ParseInt(ctx.GetHeader("Upload-Length"), 10, 64); err != nil || uploadLength < 0 {
+		c.renderError(ctx, http.StatusBadRequest, errors.New("Upload-Length不合法"))
+	} else if uploadLength > object.TusMaxSize {
+		c.renderError(ctx, http.StatusRequestEntityTooLarge, errors.New("Upload-Length超过Tus-Max-Size"))
+	} else if metadata, err := object.ParseTusMetadata(ctx.GetHeader("Upload-Metadata")); err != nil {
+		c.renderError(ctx, http.StatusBadRequest, err)
+	} else if uploadSession, err := object.CreateTusUpload(uploadLength, metadata); err != nil {
+		c.renderError(ctx, http.StatusInternalServerError, err)
+	} else {
+		ctx.Header("Location", strings.TrimSuffix(ctx.Request.URL.Path, "/")+"/"+uploadSession.UploadId)
+		c.setUploadHeaders(ctx, uploadSession)
+		ctx.Status(http.StatusCreated)
+	}
+}
+
+func (c *tusController) Head(ctx *gin.Context) {
+	if !c.checkResumable(ctx) {
+		return
+	}
+
+	if uploadSession, err := object.GetTusUpload(ctx.Param("uploadId")); err != nil {
+		c.renderError(ctx, http.StatusInternalServerError, err)
+	} else {
+		ctx.Header("Upload-Length", strconv.FormatInt(uploadSession.SourceFileSize, 10))
+		ctx.Header("Cache-Control", "no-store")
+		c.setUploadHeaders(ctx, uploadSession)
+		ctx.Status(http.StatusOK)
+	}
+}
+
+func (c *tusController) Patch(ctx *gin.Context) {
+	if !c.checkResumable(ctx) {
+		return
+	}
+
+	if ctx.ContentType() != "application/offset+octet-stream" {
+		c.renderError(ctx, http.StatusUnsupportedMediaType, errors.New("Content-Type必须为application/offset+octet-stream"))
+	} else if uploadOffset, err := strconv.ParseInt(ctx.GetHeader("Upload-Offset"), 10, 64); err != nil || uploadOffset < 0 {
+		c.renderError(ctx, http.StatusBadRequest, errors.New("Upload-Offset不合法"))
+	} else if uploadSession, err := object.PatchTusUpload(ctx.Param("uploadId"), uploadOffset, ctx.Request.Body); err != nil {
+		c.renderError(ctx, http.StatusInternalServerError, err)
+	} else {
+		c.setUploadHeaders(ctx, uploadSession)
+		ctx.Status(http.StatusNoContent)
+	}
+}
+
+func (c *tusController) Delete(ctx *gin.Context) {
+	if !c.checkResumable(ctx) {
+		return
+	}
+
+	if err := object.TerminateTusUpload(ctx.Param("uploadId")); err != nil {
+		c.renderError(ctx, http.StatusInternalServerError, err)
+	} else {
+		ctx.Header("Tus-Resumable", object.TusVersion)
+		ctx.Status(http.StatusNoContent)
+	}
+}
+
+// 部分客户端或代理不支持PATCH、DELETE，通过X-HTTP-Method-Override以POST方式发送
+func (c *tusController) Override(ctx *gin.Context) {
+	switch ctx.GetHeader("X-HTTP-Method-Override") {
+	case http.MethodPatch:
+		c.Patch(ctx)
+	case http.MethodDelete:
+		c.Delete(ctx)
+	case http.MethodHead:
+		c.Head(ctx)
+	default:
+		ctx.Status(http.StatusMethodNotAllowed)
+	}
+}
+
+func (c *tusController) checkResumable(ctx *gin.Context) bool {
+	if ctx.GetHeader("Tus-Resumable") != object.TusVersion {
+		ctx.Header("Tus-Version", object.TusVersion)
+		ctx.Status(http.StatusPreconditionFailed)
+
+		return false
+	}
+
+	return true
+}
+
+func (c *tusController) setUploadHeaders(ctx *gin.Context, uploadSession *object.UploadSession) {
+	ctx.Header("Tus-Resumable", object.TusVersion)
+	ctx.Header("Upload-Offset", strconv.FormatInt(uploadSession.UploadOffset, 10))
+
+	if uploadSession.FileId > 0 {
+		ctx.Header("Upload-File-Id", strconv.FormatInt(uploadSession.FileId, 10))
+	} else {
+		ctx.Header("Upload-Expires", carbon.Parse(uploadSession.ExpireTime).StdTime().UTC().Format(http.TimeFormat))
+	}
+}
+
+func (c *tusController) renderError(ctx *gin.Context, status int, err error) {
+	ctx.Header("Tus-Resumable", object.TusVersion)
+
+	if ctx.Request.Method == http.MethodHead || ctx.GetHeader("X-HTTP-Method-Override") == http.MethodHead {
+		if businessError, ok := err.(*winter.BusinessError); ok {
+			status = businessError.Status
+		}
+
+		ctx.Status(status)
+	} else {
+		winter.RenderErrorResult(ctx, status, err)
+	}
+}
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/easynet-cn/file-service/object"
+	"github.com/easynet-cn/winter"
+	"github.com/gin-gonic/gin"
+)
+
+type uploadSessionController struct{}
+
+var UploadSessionController = &uploadSessionController{}
+
+func (c *uploadSessionController) Initiate(ctx *gin.Context) {
+	m := &object.InitiateUploadParam{}
+
+	if err := ctx.ShouldBindJSON(&m); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else if uploadSession, err := object.InitiateUploadSession(*m); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, uploadSession)
+	}
+}
+
+func (c *uploadSessionController) InitiatePresigned(ctx *gin.Context) {
+	m := &object.InitiateUploadParam{}
+
+	if err := ctx.ShouldBindJSON(&m); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else if uploadSession, err := object.InitiatePresignedUpload(*m); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, uploadSession)
+	}
+}
+
+func (c *uploadSessionController) PresignParts(ctx *gin.Context) {
+	m := &object.PresignUploadPartsParam{}
+
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&m); err != nil {
+			winter.RenderBadRequestResult(ctx, err)
+
+			return
+		}
+	}
+
+	if parts, err := object.PresignUploadParts(ctx.Param("uploadId"), *m); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, parts)
+	}
+}
+
+func (c *uploadSessionController) UploadPart(ctx *gin.Context) {
+	if partNumber, err := strconv.Atoi(ctx.Param("partNumber")); err != nil {
+		winter.RenderBadRequestResult(ctx, err)
+	} else if ctx.Request.ContentLength < 0 {
+		winter.RenderErrorResult(ctx, http.StatusLengthRequired, errors.New("分片上传必须指定Content-Length"))
+	} else if part, err := object.UploadSessionPart(ctx.Param("uploadId"), partNumber, ctx.Request.Body, ctx.Request.ContentLength); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, part)
+	}
+}
+
+func (c *uploadSessionController) ListParts(ctx *gin.Context) {
+	if parts, err := object.ListUploadSessionParts(ctx.Param("uploadId")); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, parts)
+	}
+}
+
+func (c *uploadSessionController) Complete(ctx *gin.Context) {
+	m := &object.CompleteUploadParam{}
+
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&m); err != nil {
+			winter.RenderBadRequestResult(ctx, err)
+
+			return
+		}
+	}
+
+	if file, err := object.CompleteUploadSession(ctx.Param("uploadId"), *m); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, file)
+	}
+}
+
+func (c *uploadSessionController) Abort(ctx *gin.Context) {
+	if err := object.AbortUploadSession(ctx.Param("uploadId")); err != nil {
+		winter.RenderInternalServerErrorResult(ctx, err)
+	} else {
+		winter.RenderSuccessResult(ctx, &winter.RestResult{Status: 200, Data: true})
+	}
+}
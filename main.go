@@ -1,10 +1,18 @@
 package main
 
 import (
+	"os"
+
 	"github.com/easynet-cn/file-service/router"
 	_ "github.com/go-sql-driver/mysql"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate-secrets" {
+		router.RunMigrateAppSecrets()
+
+		return
+	}
+
 	router.RunApplication()
 }
@@ -0,0 +1,78 @@
+package object
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/winter"
+	"xorm.io/xorm"
+)
+
+const (
+	StatusDisabled    = 0 //禁用
+	StatusNormal      = 1 //正常
+	StatusReadOnly    = 2 //只读，允许查询和签发访问地址，不允许写入
+	StatusMaintenance = 3 //维护中，拒绝所有文件操作，客户端可稍后重试
+)
+
+type accessMode int
+
+const (
+	accessAny   accessMode = iota //不校验状态，用于清理未完成上传等后台任务
+	accessRead                    //读取
+	accessWrite                   //写入
+)
+
+// 禁用为0，未传状态时按正常处理，避免创建后即被禁用
+func getStatusOrNormal(status *int) int {
+	if status == nil {
+		return StatusNormal
+	}
+
+	return *status
+}
+
+// 应用和存储空间都允许时才能操作，未知状态按禁用处理
+func checkAccess(appEntity repository.App, bucketEntity repository.Bucket, mode accessMode) error {
+	if err := checkStatus("应用", appEntity.Status, mode); err != nil {
+		return err
+	}
+
+	return checkStatus("存储空间", bucketEntity.Status, mode)
+}
+
+func checkStatus(name string, status int, mode accessMode) error {
+	if mode == accessAny {
+		return nil
+	}
+
+	switch status {
+	case StatusNormal:
+		return nil
+	case StatusReadOnly:
+		if mode == accessRead {
+			return nil
+		}
+
+		return winter.NewBusinessError(http.StatusForbidden, strconv.Itoa(http.StatusForbidden), name+"只读，不允许写入")
+	case StatusMaintenance:
+		return winter.NewBusinessError(http.StatusServiceUnavailable, strconv.Itoa(http.StatusServiceUnavailable), name+"维护中，请稍后重试")
+	default:
+		return winter.NewBusinessError(http.StatusForbidden, strconv.Itoa(http.StatusForbidden), name+"已禁用")
+	}
+}
+
+func checkBucketAccessById(engine *xorm.Engine, bucketId int64, mode accessMode) error {
+	if bucketEntity, err := repository.BucketRepository.FindById(engine, bucketId); err != nil {
+		return err
+	} else if bucketEntity.Id == 0 {
+		return winter.NewBadRequestBusinessError("存储空间不存在")
+	} else if appEntity, err := repository.AppRepository.FindById(engine, bucketEntity.AppId); err != nil {
+		return err
+	} else if appEntity.Id == 0 {
+		return winter.NewBadRequestBusinessError("应用不存在")
+	} else {
+		return checkAccess(*appEntity, *bucketEntity, mode)
+	}
+}
@@ -0,0 +1,36 @@
+package object
+
+import (
+	"testing"
+
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/winter"
+)
+
+func Test_checkAccess(t *testing.T) {
+	for _, c := range []struct {
+		appStatus    int
+		bucketStatus int
+		mode         accessMode
+		status       int
+	}{
+		{StatusNormal, StatusNormal, accessWrite, 0},
+		{StatusNormal, StatusReadOnly, accessRead, 0},
+		{StatusNormal, StatusReadOnly, accessWrite, 403},
+		{StatusReadOnly, StatusNormal, accessWrite, 403},
+		{StatusNormal, StatusMaintenance, accessRead, 503},
+		{StatusMaintenance, StatusNormal, accessWrite, 503},
+		{StatusNormal, StatusDisabled, accessRead, 403},
+		{StatusDisabled, StatusMaintenance, accessRead, 403},
+		{StatusNormal, 9, accessRead, 403},
+		{StatusDisabled, StatusDisabled, accessAny, 0},
+	} {
+		err := checkAccess(repository.App{Status: c.appStatus}, repository.Bucket{Status: c.bucketStatus}, c.mode)
+
+		if c.status == 0 && err != nil {
+			t.Errorf("app %d bucket %d mode %d: unexpected error %v", c.appStatus, c.bucketStatus, c.mode, err)
+		} else if businessError, ok := err.(*winter.BusinessError); c.status != 0 && (!ok || businessError.Status != c.status) {
+			t.Errorf("app %d bucket %d mode %d: expected status %d, got %v", c.appStatus, c.bucketStatus, c.mode, c.status, err)
+		}
+	}
+}
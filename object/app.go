@@ -2,20 +2,33 @@ package object
 
 import (
 	"github.com/dromara/carbon/v2"
+	"github.com/easynet-cn/file-service/log"
 	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/storage"
 	"github.com/easynet-cn/winter"
 	"github.com/easynet-cn/winter/orm"
+	"go.uber.org/zap"
 )
 
 type App struct {
-	Id              int64  `json:"id"`
-	AccessKeyId     string `json:"accessKeyId"`
-	AccessKeySecret string `json:"accessKeySecret"`
-	Endpoint        string `json:"endpoint"`
-	InnerEndpoint   string `json:"innerEndpoint"`
-	Status          int    `json:"status"`
-	CreateTime      string `json:"createTime"`
-	UpdateTime      string `json:"updateTime"`
+	Id                       int64  `json:"id"`
+	Provider                 string `json:"provider"`
+	AccessKeyId              string `json:"accessKeyId"`               //访问秘钥ID，查询结果脱敏，更新时为空或为脱敏值表示不修改
+	AccessKeySecret          string `json:"accessKeySecret,omitempty"` //访问秘钥，只写，更新时为空表示不修改
+	SecretSet                bool   `json:"secretSet"`                 //是否已设置访问秘钥
+	SecondaryAccessKeyId     string `json:"secondaryAccessKeyId"`      //备用访问秘钥ID，查询结果脱敏，只能通过轮换接口修改
+	SecondaryAccessKeySecret string `json:"-"`                         //备用访问秘钥，不返回
+	SecondarySecretSet       bool   `json:"secondarySecretSet"`        //是否已设置备用访问秘钥
+	Endpoint                 string `json:"endpoint"`
+	InnerEndpoint            string `json:"innerEndpoint"`
+	Region                   string `json:"region"`
+	BucketLookup             int    `json:"bucketLookup"`
+	SignVersion              string `json:"signVersion"`
+	RoleArn                  string `json:"roleArn"`
+	StsEndpoint              string `json:"stsEndpoint"`
+	Status                   *int   `json:"status"` //状态，创建时为空默认正常，更新时为空表示不修改
+	CreateTime               string `json:"createTime"`
+	UpdateTime               string `json:"updateTime"`
 }
 
 func SearchApps(searchParam winter.PageParam) (winter.PageResult, error) {
@@ -30,7 +43,7 @@ func SearchApps(searchParam winter.PageParam) (winter.PageResult, error) {
 		pageResult.TotalPages = pageResult.GetTotalPages(searchParam.PageSize)
 
 		for i, m := range ms {
-			pageResult.Data[i] = m
+			pageResult.Data[i] = redactApp(m)
 		}
 
 		return *pageResult, nil
@@ -38,6 +51,14 @@ func SearchApps(searchParam winter.PageParam) (winter.PageResult, error) {
 }
 
 func CreateApp(m App) (*App, error) {
+	accessKeySecret, err := sealAppSecret(m.AccessKeySecret, "")
+
+	if err != nil {
+		return nil, err
+	}
+
+	m.AccessKeySecret = accessKeySecret
+
 	entity := AppToEntity(m)
 
 	now := carbon.Now().ToDateTimeString()
@@ -57,6 +78,8 @@ func UpdateApp(m App) (*App, error) {
 
 	if appEntity, err := repository.AppRepository.FindById(engine, m.Id); err != nil || appEntity.Id == 0 {
 		return nil, err
+	} else if m.AccessKeySecret, err = sealAppSecret(m.AccessKeySecret, appEntity.AccessKeySecret); err != nil {
+		return nil, err
 	} else if cols := getUpdateAppCols(appEntity, m); len(cols) == 0 {
 		return EntityToApp(*appEntity), nil
 	} else {
@@ -67,50 +90,126 @@ func UpdateApp(m App) (*App, error) {
 		if err := repository.AppRepository.Update(engine, cols, appEntity); err != nil {
 			return nil, err
 		} else {
+			notifyAppChanged(appEntity.Id)
+
 			return EntityToApp(*appEntity), nil
 		}
 	}
 }
 
 func DeleteAppById(id int64) (int64, error) {
-	return repository.AppRepository.DeleteById(GetDB(), id)
+	affected, err := repository.AppRepository.DeleteById(GetDB(), id)
+
+	if err == nil && affected > 0 {
+		notifyAppChanged(id)
+	}
+
+	return affected, err
 }
 
 func AppToEntity(m App) *repository.App {
 	return &repository.App{
 		Id:              m.Id,
+		Provider:        m.Provider,
 		AccessKeyId:     m.AccessKeyId,
 		AccessKeySecret: m.AccessKeySecret,
 		Endpoint:        m.Endpoint,
 		InnerEndpoint:   m.InnerEndpoint,
-		Status:          m.Status,
+		Region:          m.Region,
+		BucketLookup:    m.BucketLookup,
+		SignVersion:     m.SignVersion,
+		RoleArn:         m.RoleArn,
+		StsEndpoint:     m.StsEndpoint,
+		Status:          getStatusOrNormal(m.Status),
 		CreateTime:      m.CreateTime,
 		UpdateTime:      m.UpdateTime,
 	}
 }
 
+// 返回的视图中访问秘钥ID脱敏，不包含访问秘钥
 func EntityToApp(entity repository.App) *App {
-	return &App{
-		Id:              entity.Id,
-		AccessKeyId:     entity.AccessKeyId,
-		AccessKeySecret: entity.AccessKeySecret,
+	m := redactApp(App{
+		Id:                       entity.Id,
+		Provider:                 entity.Provider,
+		AccessKeyId:              entity.AccessKeyId,
+		AccessKeySecret:          entity.AccessKeySecret,
+		SecondaryAccessKeyId:     entity.SecondaryAccessKeyId,
+		SecondaryAccessKeySecret: entity.SecondaryAccessKeySecret,
+		Endpoint:                 entity.Endpoint,
+		InnerEndpoint:            entity.InnerEndpoint,
+		Region:                   entity.Region,
+		BucketLookup:             entity.BucketLookup,
+		SignVersion:              entity.SignVersion,
+		RoleArn:                  entity.RoleArn,
+		StsEndpoint:              entity.StsEndpoint,
+		Status:                   &entity.Status,
+		CreateTime:               entity.CreateTime,
+		UpdateTime:               entity.UpdateTime,
+	})
+
+	return &m
+}
+
+func redactApp(m App) App {
+	m.AccessKeyId = maskAccessKeyId(m.AccessKeyId)
+	m.SecretSet = m.AccessKeySecret != ""
+	m.AccessKeySecret = ""
+	m.SecondaryAccessKeyId = maskAccessKeyId(m.SecondaryAccessKeyId)
+	m.SecondarySecretSet = m.SecondaryAccessKeySecret != ""
+	m.SecondaryAccessKeySecret = ""
+
+	return m
+}
+
+func maskAccessKeyId(accessKeyId string) string {
+	if accessKeyId == "" {
+		return ""
+	} else if len(accessKeyId) <= 8 {
+		return "****"
+	}
+
+	return accessKeyId[:4] + "****" + accessKeyId[len(accessKeyId)-4:]
+}
+
+// 访问秘钥在此处解密，明文只存在于内存中
+func AppToStorageConfig(entity repository.App) (storage.Config, error) {
+	accessKeySecret, err := openAppSecret(entity.AccessKeySecret)
+
+	if err != nil {
+		log.Logger.Error("解密应用访问秘钥失败", zap.Int64("appId", entity.Id), zap.Error(err))
+
+		return storage.Config{}, err
+	}
+
+	return storage.Config{
+		Provider:        entity.Provider,
 		Endpoint:        entity.Endpoint,
 		InnerEndpoint:   entity.InnerEndpoint,
-		Status:          entity.Status,
-		CreateTime:      entity.CreateTime,
-		UpdateTime:      entity.UpdateTime,
-	}
+		AccessKeyId:     entity.AccessKeyId,
+		AccessKeySecret: accessKeySecret,
+		Region:          entity.Region,
+		BucketLookup:    entity.BucketLookup,
+		SignVersion:     entity.SignVersion,
+		RoleArn:         entity.RoleArn,
+		StsEndpoint:     entity.StsEndpoint,
+	}, nil
 }
 
 func getUpdateAppCols(entity *repository.App, m App) []string {
 	cols := make([]string, 0)
 
-	if entity.AccessKeyId != m.AccessKeyId {
+	if entity.Provider != m.Provider {
+		cols = append(cols, "provider")
+
+		entity.Provider = m.Provider
+	}
+
+	if m.AccessKeyId != "" && m.AccessKeyId != maskAccessKeyId(entity.AccessKeyId) && entity.AccessKeyId != m.AccessKeyId {
 		cols = append(cols, "access_key_id")
 
 		entity.AccessKeyId = m.AccessKeyId
 	}
-	if entity.AccessKeySecret != m.AccessKeySecret {
+	if m.AccessKeySecret != "" && entity.AccessKeySecret != m.AccessKeySecret {
 		cols = append(cols, "access_key_secret")
 
 		entity.AccessKeySecret = m.AccessKeySecret
@@ -125,10 +224,35 @@ func getUpdateAppCols(entity *repository.App, m App) []string {
 
 		entity.InnerEndpoint = m.InnerEndpoint
 	}
-	if entity.Status != m.Status {
+	if entity.Region != m.Region {
+		cols = append(cols, "region")
+
+		entity.Region = m.Region
+	}
+	if entity.BucketLookup != m.BucketLookup {
+		cols = append(cols, "bucket_lookup")
+
+		entity.BucketLookup = m.BucketLookup
+	}
+	if entity.SignVersion != m.SignVersion {
+		cols = append(cols, "sign_version")
+
+		entity.SignVersion = m.SignVersion
+	}
+	if entity.RoleArn != m.RoleArn {
+		cols = append(cols, "role_arn")
+
+		entity.RoleArn = m.RoleArn
+	}
+	if entity.StsEndpoint != m.StsEndpoint {
+		cols = append(cols, "sts_endpoint")
+
+		entity.StsEndpoint = m.StsEndpoint
+	}
+	if m.Status != nil && entity.Status != *m.Status {
 		cols = append(cols, "status")
 
-		entity.Status = m.Status
+		entity.Status = *m.Status
 	}
 
 	return cols
@@ -0,0 +1,129 @@
+package object
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/easynet-cn/file-service/log"
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/storage"
+	"github.com/easynet-cn/winter"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAppCacheChannel = "file-service:app-changed"
+)
+
+var (
+	Redis        *winter.Redis
+	backendCache = &sync.Map{}
+)
+
+// 影响存储客户端的应用字段，缓存命中时比对，用于兜底未收到变更通知的情况
+type appBackendKey struct {
+	Provider        string
+	Endpoint        string
+	InnerEndpoint   string
+	AccessKeyId     string
+	AccessKeySecret string
+	Region          string
+	BucketLookup    int
+	SignVersion     string
+	RoleArn         string
+	StsEndpoint     string
+}
+
+type backendCacheEntry struct {
+	key     appBackendKey
+	backend storage.Backend
+}
+
+func newAppBackendKey(appEntity repository.App) appBackendKey {
+	return appBackendKey{
+		Provider:        appEntity.Provider,
+		Endpoint:        appEntity.Endpoint,
+		InnerEndpoint:   appEntity.InnerEndpoint,
+		AccessKeyId:     appEntity.AccessKeyId,
+		AccessKeySecret: appEntity.AccessKeySecret,
+		Region:          appEntity.Region,
+		BucketLookup:    appEntity.BucketLookup,
+		SignVersion:     appEntity.SignVersion,
+		RoleArn:         appEntity.RoleArn,
+		StsEndpoint:     appEntity.StsEndpoint,
+	}
+}
+
+func getBackendByApp(appEntity repository.App) (storage.Backend, error) {
+	key := newAppBackendKey(appEntity)
+
+	if v, ok := backendCache.Load(appEntity.Id); ok && v.(*backendCacheEntry).key == key {
+		return v.(*backendCacheEntry).backend, nil
+	} else if config, err := AppToStorageConfig(appEntity); err != nil {
+		return nil, err
+	} else if backend, err := storage.New(config); err != nil {
+		return nil, err
+	} else {
+		backendCache.Store(appEntity.Id, &backendCacheEntry{key: key, backend: backend})
+
+		return backend, nil
+	}
+}
+
+func evictAppBackend(appId int64) {
+	backendCache.Delete(appId)
+}
+
+// 清除本地缓存并通知其他实例，通知失败只记录日志，其他实例会在下次使用时比对应用配置
+func notifyAppChanged(appId int64) {
+	evictAppBackend(appId)
+
+	if client := getRedisClient(); client != nil {
+		if err := client.Publish(context.Background(), getAppCacheChannel(), strconv.FormatInt(appId, 10)).Err(); err != nil {
+			log.Logger.Error("发布应用变更通知失败", zap.Int64("appId", appId), zap.Error(err))
+		}
+	}
+}
+
+// SubscribeAppChanged 订阅应用变更通知，收到后清除本地存储客户端缓存，未配置Redis时不订阅
+func SubscribeAppChanged(ctx context.Context) {
+	client := getRedisClient()
+
+	if client == nil {
+		return
+	}
+
+	pubsub := client.Subscribe(ctx, getAppCacheChannel())
+
+	go func() {
+		defer pubsub.Close()
+
+		for message := range pubsub.Channel() {
+			if appId, err := strconv.ParseInt(message.Payload, 10, 64); err != nil {
+				log.Logger.Warn("应用变更通知格式不正确", zap.String("payload", message.Payload))
+			} else {
+				evictAppBackend(appId)
+			}
+		}
+	}()
+}
+
+func getRedisClient() *redis.Client {
+	if Redis == nil {
+		return nil
+	}
+
+	return Redis.GetRedisClient()
+}
+
+func getAppCacheChannel() string {
+	if Config != nil {
+		if channel := Config.GetString("file.app-cache.channel"); channel != "" {
+			return channel
+		}
+	}
+
+	return defaultAppCacheChannel
+}
@@ -0,0 +1,79 @@
+package object
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/storage"
+	"github.com/easynet-cn/winter"
+	"github.com/spf13/viper"
+)
+
+func Test_getBackendByApp(t *testing.T) {
+	appEntity := repository.App{Id: 1001, Provider: storage.ProviderLocal, Endpoint: t.TempDir(), AccessKeySecret: "secret"}
+
+	defer evictAppBackend(appEntity.Id)
+
+	backend, err := getBackendByApp(appEntity)
+
+	if err != nil {
+		t.Fatal(err)
+	} else if cached, _ := getBackendByApp(appEntity); cached != backend {
+		t.Error("expected cached backend")
+	}
+
+	appEntity.AccessKeySecret = "other"
+
+	changed, _ := getBackendByApp(appEntity)
+
+	if changed == backend {
+		t.Error("expected new backend after app changed")
+	}
+
+	evictAppBackend(appEntity.Id)
+
+	if rebuilt, _ := getBackendByApp(appEntity); rebuilt == changed {
+		t.Error("expected new backend after eviction")
+	}
+}
+
+func TestSubscribeAppChanged(t *testing.T) {
+	addr := os.Getenv("REDIS_TEST_ADDR")
+
+	if addr == "" {
+		t.Skip("REDIS_TEST_ADDR not set")
+	}
+
+	host, port, _ := strings.Cut(addr, ":")
+	v := viper.New()
+
+	v.Set("spring.redis.default.host", host)
+	v.Set("spring.redis.default.port", port)
+
+	Redis = winter.NewRedis(v)
+	Redis.Init()
+
+	defer func() { Redis = nil }()
+
+	SubscribeAppChanged(t.Context())
+
+	appEntity := repository.App{Id: 1002, Provider: storage.ProviderLocal, Endpoint: t.TempDir(), AccessKeySecret: "secret"}
+
+	if _, err := getBackendByApp(appEntity); err != nil {
+		t.Fatal(err)
+	}
+
+	// 订阅在后台建立，重复发布直到本地缓存被清除
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(100 * time.Millisecond) {
+		if err := getRedisClient().Publish(t.Context(), getAppCacheChannel(), "1002").Err(); err != nil {
+			t.Fatal(err)
+		} else if _, ok := backendCache.Load(appEntity.Id); !ok {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatal("expected backend evicted by broadcast")
+		}
+	}
+}
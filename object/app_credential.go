@@ -0,0 +1,126 @@
+package object
+
+import (
+	"github.com/dromara/carbon/v2"
+	"github.com/easynet-cn/file-service/log"
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/storage"
+	"github.com/easynet-cn/winter"
+	"go.uber.org/zap"
+	"xorm.io/xorm"
+)
+
+type AppCredentialParam struct {
+	Id              int64  `json:"-"`                                  //应用ID
+	AccessKeyId     string `json:"accessKeyId" binding:"required"`     //访问秘钥ID
+	AccessKeySecret string `json:"accessKeySecret" binding:"required"` //访问秘钥
+}
+
+// SetAppSecondaryCredential 设置备用访问秘钥，轮换时先设置新秘钥，校验通过后再切换
+func SetAppSecondaryCredential(param AppCredentialParam) (*App, error) {
+	engine := GetDB()
+
+	if appEntity, err := repository.AppRepository.FindById(engine, param.Id); err != nil {
+		return nil, err
+	} else if appEntity.Id == 0 {
+		return nil, winter.NewNotFoundBusinessError("应用不存在")
+	} else if accessKeySecret, err := sealAppSecret(param.AccessKeySecret, appEntity.SecondaryAccessKeySecret); err != nil {
+		return nil, err
+	} else {
+		appEntity.SecondaryAccessKeyId = param.AccessKeyId
+		appEntity.SecondaryAccessKeySecret = accessKeySecret
+
+		return updateAppCredentials(engine, appEntity, false)
+	}
+}
+
+// CutoverAppCredential 使用备用访问秘钥访问应用的存储空间，成功后与主访问秘钥互换，原主秘钥保留为备用秘钥以便回滚
+func CutoverAppCredential(id int64) (*App, error) {
+	engine := GetDB()
+
+	appEntity, err := repository.AppRepository.FindById(engine, id)
+
+	if err != nil {
+		return nil, err
+	} else if appEntity.Id == 0 {
+		return nil, winter.NewNotFoundBusinessError("应用不存在")
+	} else if appEntity.SecondaryAccessKeyId == "" || appEntity.SecondaryAccessKeySecret == "" {
+		return nil, winter.NewBadRequestBusinessError("未设置备用访问秘钥")
+	}
+
+	candidate := *appEntity
+
+	candidate.AccessKeyId, candidate.SecondaryAccessKeyId = appEntity.SecondaryAccessKeyId, appEntity.AccessKeyId
+	candidate.AccessKeySecret, candidate.SecondaryAccessKeySecret = appEntity.SecondaryAccessKeySecret, appEntity.AccessKeySecret
+
+	if err := verifyAppCredential(engine, candidate); err != nil {
+		return nil, err
+	}
+
+	return updateAppCredentials(engine, &candidate, true)
+}
+
+// DeleteAppSecondaryCredential 删除备用访问秘钥，在云端禁用旧秘钥后调用
+func DeleteAppSecondaryCredential(id int64) (*App, error) {
+	engine := GetDB()
+
+	if appEntity, err := repository.AppRepository.FindById(engine, id); err != nil {
+		return nil, err
+	} else if appEntity.Id == 0 {
+		return nil, winter.NewNotFoundBusinessError("应用不存在")
+	} else {
+		appEntity.SecondaryAccessKeyId = ""
+		appEntity.SecondaryAccessKeySecret = ""
+
+		return updateAppCredentials(engine, appEntity, false)
+	}
+}
+
+func updateAppCredentials(engine *xorm.Engine, appEntity *repository.App, primary bool) (*App, error) {
+	cols := []string{"secondary_access_key_id", "secondary_access_key_secret", "update_time"}
+
+	if primary {
+		cols = append(cols, "access_key_id", "access_key_secret")
+	}
+
+	appEntity.UpdateTime = carbon.Now().ToDateTimeString()
+
+	if err := repository.AppRepository.Update(engine, cols, appEntity); err != nil {
+		return nil, err
+	}
+
+	if primary {
+		notifyAppChanged(appEntity.Id)
+	}
+
+	return EntityToApp(*appEntity), nil
+}
+
+// 使用候选秘钥新建客户端（不使用缓存）列举应用下第一个存储空间，应用未绑定存储空间时只校验客户端创建
+func verifyAppCredential(engine *xorm.Engine, appEntity repository.App) error {
+	config, err := AppToStorageConfig(appEntity)
+
+	if err != nil {
+		return err
+	}
+
+	backend, err := storage.New(config)
+
+	if err != nil {
+		return winter.NewBadRequestBusinessError("备用访问秘钥校验失败：" + err.Error())
+	}
+
+	buckets, err := repository.BucketRepository.FindByAppId(engine, appEntity.Id)
+
+	if err != nil {
+		return err
+	} else if len(buckets) == 0 {
+		return nil
+	} else if _, err := backend.List(buckets[0].Name, "", "", 1); err != nil {
+		log.Logger.Warn("备用访问秘钥校验失败", zap.Int64("appId", appEntity.Id), zap.String("bucket", buckets[0].Name), zap.Error(err))
+
+		return winter.NewBadRequestBusinessError("备用访问秘钥校验失败：" + err.Error())
+	}
+
+	return nil
+}
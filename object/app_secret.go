@@ -0,0 +1,215 @@
+package object
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"sync"
+
+	"github.com/dromara/carbon/v2"
+	"github.com/easynet-cn/file-service/log"
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/secret"
+	"github.com/easynet-cn/winter"
+	"go.uber.org/zap"
+)
+
+const (
+	appSecretAccessSuccess = 0
+	appSecretAccessDenied  = 1
+	appSecretAccessFailed  = 2
+)
+
+var (
+	appSecretKeyProvider     secret.KeyProvider
+	appSecretKeyProviderErr  error
+	appSecretKeyProviderOnce sync.Once
+)
+
+type AppSecretMigrationResult struct {
+	Total     int `json:"total"`     //秘钥总数（主、备用秘钥分别计数）
+	Encrypted int `json:"encrypted"` //新加密数量
+	Rotated   int `json:"rotated"`   //更换主密钥数量
+	Skipped   int `json:"skipped"`   //无需处理数量
+	Failed    int `json:"failed"`    //失败数量
+}
+
+type AppSecretRevealParam struct {
+	Id       int64  `json:"id"`                          //应用ID
+	Operator string `json:"operator" binding:"required"` //操作人
+	Reason   string `json:"reason" binding:"required"`   //查看原因
+	Token    string `json:"-"`                           //查看令牌，来自请求头
+	ClientIp string `json:"-"`                           //客户端IP
+}
+
+type AppSecret struct {
+	Id              int64  `json:"id"`              //应用ID
+	AccessKeyId     string `json:"accessKeyId"`     //访问秘钥ID
+	AccessKeySecret string `json:"accessKeySecret"` //访问秘钥
+}
+
+// RevealAppSecret 查看应用访问秘钥明文，需要配置的查看令牌，每次查看（包括被拒绝的）都会记录
+func RevealAppSecret(param AppSecretRevealParam) (*AppSecret, error) {
+	engine := GetDB()
+	accessLog := &repository.AppSecretAccessLog{
+		AppId:      param.Id,
+		Operator:   truncateString(param.Operator, 100),
+		Reason:     truncateString(param.Reason, 500),
+		ClientIp:   param.ClientIp,
+		Result:     appSecretAccessFailed,
+		CreateTime: carbon.Now().ToDateTimeString(),
+	}
+
+	defer func() {
+		log.Logger.Warn("查看应用访问秘钥", zap.Any("accessLog", accessLog))
+
+		if err := repository.AppSecretAccessLogRepository.Create(engine, accessLog); err != nil {
+			log.Logger.Error("repository.CreateAppSecretAccessLog", zap.Any("accessLog", accessLog), zap.Error(err))
+		}
+	}()
+
+	revealToken := ""
+
+	if Config != nil {
+		revealToken = Config.GetString("file.secret.reveal-token")
+	}
+
+	if revealToken == "" {
+		accessLog.Result = appSecretAccessDenied
+
+		return nil, winter.NewBusinessError(http.StatusForbidden, "403", "未开启查看访问秘钥")
+	} else if subtle.ConstantTimeCompare([]byte(param.Token), []byte(revealToken)) != 1 {
+		accessLog.Result = appSecretAccessDenied
+
+		return nil, winter.NewBusinessError(http.StatusForbidden, "403", "无权查看访问秘钥")
+	}
+
+	if appEntity, err := repository.AppRepository.FindById(engine, param.Id); err != nil {
+		return nil, err
+	} else if appEntity.Id == 0 {
+		return nil, winter.NewNotFoundBusinessError("应用不存在")
+	} else if accessKeySecret, err := openAppSecret(appEntity.AccessKeySecret); err != nil {
+		return nil, err
+	} else {
+		accessLog.Result = appSecretAccessSuccess
+
+		return &AppSecret{Id: appEntity.Id, AccessKeyId: appEntity.AccessKeyId, AccessKeySecret: accessKeySecret}, nil
+	}
+}
+
+// 未配置主密钥时返回nil，此时秘钥按明文保存
+func getAppSecretKeyProvider() (secret.KeyProvider, error) {
+	appSecretKeyProviderOnce.Do(func() {
+		if Config == nil {
+			return
+		}
+
+		config := secret.Config{
+			Provider:       Config.GetString("file.secret.provider"),
+			CurrentVersion: Config.GetString("file.secret.current-version"),
+			MasterKeys:     Config.GetStringMapString("file.secret.master-keys"),
+			KeyFile:        Config.GetString("file.secret.key-file"),
+		}
+
+		if config.Provider == "" && len(config.MasterKeys) == 0 && config.KeyFile == "" {
+			return
+		}
+
+		if appSecretKeyProvider, appSecretKeyProviderErr = secret.New(config); appSecretKeyProviderErr != nil {
+			log.Logger.Error("加载主密钥失败", zap.Error(appSecretKeyProviderErr))
+		}
+	})
+
+	return appSecretKeyProvider, appSecretKeyProviderErr
+}
+
+// 加密待保存的访问秘钥：与已保存的值一致或解密后一致时沿用已保存的密文，已是密文时校验可解密后原样保存
+func sealAppSecret(value string, stored string) (string, error) {
+	if value == "" || value == stored {
+		return value, nil
+	}
+
+	provider, err := getAppSecretKeyProvider()
+
+	if err != nil {
+		return "", err
+	} else if secret.IsEncrypted(value) {
+		_, err := secret.Decrypt(provider, value)
+
+		return value, err
+	} else if provider == nil {
+		return value, nil
+	} else if plaintext, err := secret.Decrypt(provider, stored); err == nil && stored != "" && plaintext == value {
+		return stored, nil
+	}
+
+	return secret.Encrypt(provider, value)
+}
+
+func openAppSecret(value string) (string, error) {
+	if !secret.IsEncrypted(value) {
+		return value, nil
+	} else if provider, err := getAppSecretKeyProvider(); err != nil {
+		return "", err
+	} else {
+		return secret.Decrypt(provider, value)
+	}
+}
+
+// MigrateAppSecrets 加密存量明文访问秘钥，并将旧版本主密钥加密的秘钥更换为当前主密钥
+func MigrateAppSecrets() (*AppSecretMigrationResult, error) {
+	provider, err := getAppSecretKeyProvider()
+
+	if err != nil {
+		return nil, err
+	} else if provider == nil {
+		return nil, secret.ErrNoKeyProvider
+	}
+
+	engine := GetDB()
+
+	entities, err := repository.AppRepository.FindAllIncludingDeleted(engine)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AppSecretMigrationResult{}
+
+	for i := range entities {
+		entity := &entities[i]
+
+		for col, value := range map[string]*string{"access_key_secret": &entity.AccessKeySecret, "secondary_access_key_secret": &entity.SecondaryAccessKeySecret} {
+			result.Total++
+
+			encrypted := secret.IsEncrypted(*value)
+
+			if *value == "" || (encrypted && secret.KeyVersion(*value) == provider.CurrentVersion()) {
+				result.Skipped++
+
+				continue
+			}
+
+			plaintext, err := secret.Decrypt(provider, *value)
+
+			if err == nil {
+				*value, err = secret.Encrypt(provider, plaintext)
+			}
+
+			if err == nil {
+				err = repository.AppRepository.Update(engine, []string{col}, entity)
+			}
+
+			if err != nil {
+				log.Logger.Error("加密应用访问秘钥失败", zap.Int64("appId", entity.Id), zap.String("col", col), zap.Error(err))
+
+				result.Failed++
+			} else if encrypted {
+				result.Rotated++
+			} else {
+				result.Encrypted++
+			}
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,43 @@
+package object
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/easynet-cn/file-service/secret"
+)
+
+func Test_sealAppSecret(t *testing.T) {
+	provider, err := secret.NewLocalKeyProvider(map[string][]byte{"v1": bytes.Repeat([]byte{1}, 32)}, "v1")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	appSecretKeyProviderOnce.Do(func() {})
+	appSecretKeyProvider = provider
+
+	defer func() { appSecretKeyProvider = nil }()
+
+	sealed, err := sealAppSecret("secret", "")
+
+	if err != nil {
+		t.Fatal(err)
+	} else if !secret.IsEncrypted(sealed) {
+		t.Fatalf("expected ciphertext, got %s", sealed)
+	} else if plaintext, err := openAppSecret(sealed); err != nil || plaintext != "secret" {
+		t.Errorf("expected secret, got %q %v", plaintext, err)
+	}
+
+	if v, err := sealAppSecret("secret", sealed); err != nil || v != sealed {
+		t.Errorf("expected stored ciphertext reused, got %q %v", v, err)
+	} else if v, err := sealAppSecret(sealed, "plain"); err != nil || v != sealed {
+		t.Errorf("expected ciphertext kept, got %q %v", v, err)
+	} else if _, err := sealAppSecret(sealed[:len(sealed)-4], ""); err == nil {
+		t.Error("expected invalid ciphertext rejected")
+	} else if v, err := sealAppSecret("other", sealed); err != nil || v == sealed || !secret.IsEncrypted(v) {
+		t.Errorf("expected new ciphertext, got %q %v", v, err)
+	} else if v, err := openAppSecret("plain"); err != nil || v != "plain" {
+		t.Errorf("expected plaintext passthrough, got %q %v", v, err)
+	}
+}
@@ -0,0 +1,47 @@
+package object
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/easynet-cn/file-service/repository"
+)
+
+func TestEntityToApp(t *testing.T) {
+	m := EntityToApp(repository.App{Id: 1, AccessKeyId: "LTAI5tAbcdefWxyz", AccessKeySecret: "secret"})
+
+	if m.AccessKeyId != "LTAI****Wxyz" || m.AccessKeySecret != "" || !m.SecretSet {
+		t.Errorf("unexpected app view: %+v", m)
+	} else if m := EntityToApp(repository.App{Id: 1, AccessKeyId: "ak"}); m.AccessKeyId != "****" || m.SecretSet {
+		t.Errorf("unexpected app view: %+v", m)
+	}
+}
+
+func Test_getUpdateAppCols(t *testing.T) {
+	entity := &repository.App{AccessKeyId: "LTAI5tAbcdefWxyz", AccessKeySecret: "secret"}
+
+	if cols := getUpdateAppCols(entity, App{AccessKeyId: "LTAI****Wxyz"}); len(cols) != 0 || entity.AccessKeyId != "LTAI5tAbcdefWxyz" || entity.AccessKeySecret != "secret" {
+		t.Errorf("expected masked and omitted credentials unchanged, got %v %+v", cols, entity)
+	} else if cols := getUpdateAppCols(entity, App{AccessKeyId: "LTAI5tNewKey0000", AccessKeySecret: "other"}); !slices.Equal(cols, []string{"access_key_id", "access_key_secret"}) || entity.AccessKeySecret != "other" {
+		t.Errorf("expected credentials updated, got %v %+v", cols, entity)
+	}
+
+	status := StatusReadOnly
+	entity = &repository.App{Status: StatusNormal}
+
+	if cols := getUpdateAppCols(entity, App{}); len(cols) != 0 || entity.Status != StatusNormal {
+		t.Errorf("expected omitted status unchanged, got %v %+v", cols, entity)
+	} else if cols := getUpdateAppCols(entity, App{Status: &status}); !slices.Equal(cols, []string{"status"}) || entity.Status != StatusReadOnly {
+		t.Errorf("expected status updated, got %v %+v", cols, entity)
+	}
+}
+
+func TestAppToEntity(t *testing.T) {
+	status := StatusDisabled
+
+	if entity := AppToEntity(App{}); entity.Status != StatusNormal {
+		t.Errorf("expected omitted status normal, got %d", entity.Status)
+	} else if entity := AppToEntity(App{Status: &status}); entity.Status != StatusDisabled {
+		t.Errorf("expected status disabled, got %d", entity.Status)
+	}
+}
@@ -9,15 +9,18 @@ import (
 )
 
 type Bucket struct {
-	Id            int64          `json:"id"`
-	AppId         int64          `json:"appId"`
-	BucketType    int            `json:"bucketType"`
-	Name          string         `json:"name"`
-	Domain        string         `json:"domain"`
-	ProcessConfig *ProcessConfig `json:"processConfig"`
-	Status        int            `json:"status"`
-	CreateTime    string         `json:"createTime"`
-	UpdateTime    string         `json:"updateTime"`
+	Id                 int64          `json:"id"`
+	AppId              int64          `json:"appId"`
+	BucketType         int            `json:"bucketType"`
+	Name               string         `json:"name"`
+	Domain             string         `json:"domain"`
+	ProcessConfig      *ProcessConfig `json:"processConfig"`
+	UploadConfig       *UploadConfig  `json:"uploadConfig"`
+	TrashRetentionDays int            `json:"trashRetentionDays"`
+	Dedup              int            `json:"dedup"`
+	Status             *int           `json:"status"` //状态，创建时为空默认正常，更新时为空表示不修改
+	CreateTime         string         `json:"createTime"`
+	UpdateTime         string         `json:"updateTime"`
 }
 
 func SearchBuckets(searchParam winter.PageParam) (winter.PageResult, error) {
@@ -90,35 +93,45 @@ func DeleteBucketById(id int64) (int64, error) {
 
 func BucketToEntity(m Bucket) *repository.Bucket {
 	entity := &repository.Bucket{
-		Id:         m.Id,
-		AppId:      m.AppId,
-		BucketType: m.BucketType,
-		Name:       m.Name,
-		Domain:     m.Domain,
-		Status:     m.Status,
-		CreateTime: m.CreateTime,
-		UpdateTime: m.UpdateTime,
+		Id:                 m.Id,
+		AppId:              m.AppId,
+		BucketType:         m.BucketType,
+		Name:               m.Name,
+		Domain:             m.Domain,
+		TrashRetentionDays: m.TrashRetentionDays,
+		Dedup:              m.Dedup,
+		Status:             getStatusOrNormal(m.Status),
+		CreateTime:         m.CreateTime,
+		UpdateTime:         m.UpdateTime,
 	}
 
 	if m.ProcessConfig != nil {
-		if bytes, err := json.Marshal(m.ProcessConfig); err != nil {
+		if bytes, err := json.Marshal(m.ProcessConfig); err == nil {
 			entity.ProcessConfig = string(bytes)
 		}
 	}
 
+	if m.UploadConfig != nil {
+		if bytes, err := json.Marshal(m.UploadConfig); err == nil {
+			entity.UploadConfig = string(bytes)
+		}
+	}
+
 	return entity
 }
 
 func EntityToBucket(entity repository.Bucket) *Bucket {
 	m := &Bucket{
-		Id:         entity.Id,
-		AppId:      entity.AppId,
-		BucketType: entity.BucketType,
-		Name:       entity.Name,
-		Domain:     entity.Domain,
-		Status:     entity.Status,
-		CreateTime: entity.CreateTime,
-		UpdateTime: entity.UpdateTime,
+		Id:                 entity.Id,
+		AppId:              entity.AppId,
+		BucketType:         entity.BucketType,
+		Name:               entity.Name,
+		Domain:             entity.Domain,
+		TrashRetentionDays: entity.TrashRetentionDays,
+		Dedup:              entity.Dedup,
+		Status:             &entity.Status,
+		CreateTime:         entity.CreateTime,
+		UpdateTime:         entity.UpdateTime,
 	}
 
 	if entity.ProcessConfig != "" {
@@ -129,6 +142,8 @@ func EntityToBucket(entity repository.Bucket) *Bucket {
 		}
 	}
 
+	m.UploadConfig = parseUploadConfig(entity.UploadConfig)
+
 	return m
 }
 
@@ -155,10 +170,25 @@ func getUpdateBucketCols(entity *repository.Bucket, m Bucket) []string {
 
 		entity.Domain = m.Domain
 	}
-	if entity.Status != m.Status {
+	if uploadConfig := BucketToEntity(m).UploadConfig; entity.UploadConfig != uploadConfig {
+		cols = append(cols, "upload_config")
+
+		entity.UploadConfig = uploadConfig
+	}
+	if entity.TrashRetentionDays != m.TrashRetentionDays {
+		cols = append(cols, "trash_retention_days")
+
+		entity.TrashRetentionDays = m.TrashRetentionDays
+	}
+	if entity.Dedup != m.Dedup {
+		cols = append(cols, "dedup")
+
+		entity.Dedup = m.Dedup
+	}
+	if m.Status != nil && entity.Status != *m.Status {
 		cols = append(cols, "status")
 
-		entity.Status = m.Status
+		entity.Status = *m.Status
 	}
 
 	return cols
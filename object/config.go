@@ -0,0 +1,59 @@
+package object
+
+import (
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/winter"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultUploadSpoolMaxSize = int64(32 * 1024 * 1024)
+)
+
+var (
+	Config *viper.Viper
+)
+
+func getOssCallbackUrl() string {
+	if Config == nil {
+		return ""
+	}
+
+	return Config.GetString("file.oss-callback-url")
+}
+
+func getOssCallbackSecret() string {
+	if Config == nil {
+		return ""
+	}
+
+	return Config.GetString("file.oss-callback-secret")
+}
+
+// 未配置暂存存储空间时暂存在上传的私有存储空间中；公有存储空间中的暂存数据可按固定key被任何人读取，必须配置私有的暂存存储空间
+func getTusStagingBucket(bucketEntity repository.Bucket) (string, error) {
+	if Config != nil && Config.GetString("file.tus.staging-bucket") != "" {
+		return Config.GetString("file.tus.staging-bucket"), nil
+	} else if bucketEntity.BucketType != 2 {
+		return "", winter.NewBadRequestBusinessError("公有存储空间使用tus上传时需要配置私有的暂存存储空间file.tus.staging-bucket")
+	}
+
+	return bucketEntity.Name, nil
+}
+
+func getTusStagingPrefix() string {
+	if Config == nil || Config.GetString("file.tus.staging-prefix") == "" {
+		return defaultTusStagingPrefix
+	}
+
+	return Config.GetString("file.tus.staging-prefix")
+}
+
+// 表单上传时文件位于bucket字段之前需要先暂存到临时文件，限制暂存大小，避免占满磁盘
+func GetUploadSpoolMaxSize() int64 {
+	if Config != nil && Config.GetInt64("file.upload.max-spool-size") > 0 {
+		return Config.GetInt64("file.upload.max-spool-size")
+	}
+
+	return defaultUploadSpoolMaxSize
+}
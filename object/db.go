@@ -21,5 +21,11 @@ func SyncDB() error {
 		&repository.App{},
 		&repository.Bucket{},
 		&repository.File{},
+		&repository.FileObject{},
+		&repository.UploadSession{},
+		&repository.FileAudit{},
+		&repository.FileAuditLock{},
+		&repository.FileAuditIssue{},
+		&repository.AppSecretAccessLog{},
 	)
 }
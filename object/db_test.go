@@ -0,0 +1,73 @@
+package object
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/easynet-cn/file-service/log"
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/storage"
+	"github.com/easynet-cn/winter"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"xorm.io/xorm"
+
+	_ "modernc.org/sqlite"
+)
+
+// 使用临时SQLite数据库替换全局数据库，并创建一个本地存储应用
+func setupTestDB(t *testing.T) (*xorm.Engine, repository.App) {
+	t.Helper()
+
+	if log.Logger == nil {
+		log.Logger = zap.NewNop()
+	}
+
+	dir := t.TempDir()
+	v := viper.New()
+
+	v.Set("spring.datasources.file.type", "sqlite")
+	v.Set("spring.datasources.file.url", filepath.Join(dir, "file.db"))
+
+	database, defaultDatabase := winter.NewDatabase(v), Database
+
+	database.Init()
+
+	Database = database
+
+	t.Cleanup(func() {
+		GetDB().Close()
+
+		Database = defaultDatabase
+	})
+
+	if err := SyncDB(); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := GetDB()
+	now := "2026-01-01 00:00:00"
+	appEntity := repository.App{Provider: storage.ProviderLocal, Endpoint: filepath.Join(dir, "data"), AccessKeySecret: "secret", Status: StatusNormal, CreateTime: now, UpdateTime: now}
+
+	if _, err := engine.Insert(&appEntity); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { evictAppBackend(appEntity.Id) })
+
+	return engine, appEntity
+}
+
+func createTestBucket(t *testing.T, engine *xorm.Engine, bucketEntity repository.Bucket) repository.Bucket {
+	t.Helper()
+
+	bucketEntity.Status = StatusNormal
+	bucketEntity.CreateTime = "2026-01-01 00:00:00"
+	bucketEntity.UpdateTime = bucketEntity.CreateTime
+
+	if _, err := engine.Insert(&bucketEntity); err != nil {
+		t.Fatal(err)
+	}
+
+	return bucketEntity
+}
@@ -0,0 +1,14 @@
+package object
+
+type DeleteFileParam struct {
+	Bucket   string   `json:"bucket"`   //bucket名称，按文件key删除时必填
+	Ids      []int64  `json:"ids"`      //文件ID集合
+	FileKeys []string `json:"fileKeys"` //文件key集合
+}
+
+type DeleteFileResult struct {
+	Id      int64  `json:"id"`      //文件ID
+	FileKey string `json:"fileKey"` //文件key
+	Success bool   `json:"success"` //是否成功
+	Message string `json:"message"` //失败原因
+}
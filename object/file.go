@@ -1,23 +1,18 @@
 package object
 
 import (
-	"crypto/hmac"
-	"crypto/sha1"
-	"encoding/base64"
 	"encoding/json"
-	"fmt"
-	"os"
+	"io"
+	"mime"
+	"net/http"
 	"path/filepath"
-	"strconv"
 	"strings"
-	"sync"
-	"time"
 
 	"github.com/Knetic/govaluate"
-	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 	"github.com/dromara/carbon/v2"
 	"github.com/easynet-cn/file-service/log"
 	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/storage"
 	"github.com/easynet-cn/winter"
 	"go.uber.org/zap"
 	"xorm.io/xorm"
@@ -33,14 +28,22 @@ type File struct {
 	SourceFileSize int64  `json:"sourceFileSize"`
 	SourceFileType string `json:"sourceFileType"`
 	SourceFileAttr string `json:"sourceFileAttr"`
+	ContentType    string `json:"contentType"`
+	ETag           string `xorm:"'etag'" json:"etag"`
+	Sha256         string `json:"sha256"`
+	Md5            string `json:"md5"`
+	Crc64          string `json:"crc64"`
+	ImageWidth     int    `json:"imageWidth"`
+	ImageHeight    int    `json:"imageHeight"`
+	ImageFormat    string `json:"imageFormat"`
+	Status         int    `json:"status"`
 	Url            string `json:"url"`
 	CreateTime     string `json:"createTime"`
 	UpdateTime     string `json:"updateTime"`
 }
 
 var (
-	ossClientCache = &sync.Map{}
-	functions      = map[string]govaluate.ExpressionFunction{
+	functions = map[string]govaluate.ExpressionFunction{
 		"hasPrefix": func(args ...any) (any, error) {
 			return strings.HasPrefix(args[0].(string), args[1].(string)), nil
 		},
@@ -56,11 +59,7 @@ var (
 	}
 )
 
-func UploadFile(uploadFile OssUploadFile, file string) (*File, error) {
-	defer func(file string) {
-		os.Remove(file)
-	}(file)
-
+func UploadFile(uploadFile OssUploadFile, reader io.Reader) (*File, error) {
 	engine := GetDB()
 
 	if ossBucket, err := repository.BucketRepository.FindByName(engine, uploadFile.Bucket); err != nil || ossBucket.Id == 0 {
@@ -71,60 +70,87 @@ func UploadFile(uploadFile OssUploadFile, file string) (*File, error) {
 		log.Logger.Error("repository.FindAppById", zap.Any("appId", ossBucket.AppId), zap.Error(err))
 
 		return nil, err
-	} else if ossClient, err := getOssClientByBucket(*appEntity); err != nil {
-		log.Logger.Error("getOssClientByBucket", zap.Any("appEntity", appEntity), zap.Error(err))
+	} else if err := checkAccess(*appEntity, *ossBucket, accessWrite); err != nil {
+		return nil, err
+	} else if backend, err := getBackendByApp(*appEntity); err != nil {
+		log.Logger.Error("getBackendByApp", zap.Int64("appId", appEntity.Id), zap.Error(err))
 
 		return nil, err
 	} else {
 		fileKey := generateFileKey(uploadFile)
+		contentType := uploadFile.ContentType
+
+		if contentType == "" {
+			contentType = mime.TypeByExtension(filepath.Ext(uploadFile.SourceFile))
+		}
+
+		result, err := putStream(backend, ossBucket.Name, fileKey, reader, streamUploadOptions{ContentType: contentType, ContentMD5: uploadFile.ContentMD5, Sha256: uploadFile.Sha256})
+
+		if err != nil {
+			log.Logger.Error("putStream", zap.String("fileKey", fileKey), zap.Error(err))
+
+			return nil, err
+		}
+
+		uploadedFileKey := fileKey
+
+		if dedupEntity := findDedupFile(engine, *ossBucket, backend, uploadFile, result.Sha256, result.Size); dedupEntity != nil {
+			fileKey = dedupEntity.FileKey
+		}
+
+		now := carbon.Now().ToDateTimeString()
 
 		fileEntity := &repository.File{
 			BucketId:       ossBucket.Id,
 			FileKey:        fileKey,
 			SourceFile:     uploadFile.SourceFile,
 			SourceFileType: uploadFile.SourceFileType,
-			SourceFileSize: uploadFile.SourceFileSize,
+			SourceFileSize: result.Size,
 			SourceFileAttr: uploadFile.SourceFileAttr,
+			ContentType:    contentType,
+			Sha256:         result.Sha256,
+			Md5:            result.Md5,
+			Crc64:          result.Crc64,
+			CreateTime:     now,
+			UpdateTime:     now,
 		}
 
-		fileEntity.BucketId = ossBucket.Id
-		fileEntity.FileKey = fileKey
-		fileEntity.SourceFile = uploadFile.SourceFile
-		fileEntity.SourceFileSize = uploadFile.SourceFileSize
-
-		now := carbon.Now().ToDateTimeString()
-
-		fileEntity.CreateTime = now
-		fileEntity.UpdateTime = now
-
 		if err := repository.FileRepository.Create(engine, fileEntity); err != nil || fileEntity.Id == 0 {
 			log.Logger.Error("repository.CreateFile", zap.Any("fileEntity", fileEntity), zap.Error(err))
 
-			return nil, err
-		}
-
-		if bucket, err := ossClient.Bucket(ossBucket.Name); err != nil {
-			log.Logger.Error("ossClient.Bucket", zap.String("bucketName", ossBucket.Name), zap.Error(err))
+			if uploadedFileKey != fileKey {
+				detachFileObject(engine, ossBucket.Id, fileKey)
+			}
 
-			return nil, err
-		} else if err := ossUploadFile(bucket, fileKey, file); err != nil {
-			log.Logger.Error("bucket.UploadFile", zap.String("fileKey", fileKey), zap.String("file", file), zap.Error(err))
+			if err := backend.Delete(ossBucket.Name, uploadedFileKey); err != nil {
+				log.Logger.Error("backend.Delete", zap.String("fileKey", uploadedFileKey), zap.Error(err))
+			}
 
 			return nil, err
-		} else {
-			return &File{
-				Id:             fileEntity.Id,
-				BucketId:       ossBucket.Id,
-				BucketName:     ossBucket.Name,
-				Domain:         ossBucket.Domain,
-				FileKey:        fileKey,
-				SourceFile:     uploadFile.SourceFile,
-				SourceFileSize: uploadFile.SourceFileSize,
-				SourceFileType: uploadFile.SourceFileType,
-				SourceFileAttr: uploadFile.SourceFileAttr,
-				Url:            getUrl(ossClient, *appEntity, *ossBucket, fileKey, uploadFile.ExpiredInSec, uploadFile.ProcessParams),
-			}, nil
+		} else if uploadedFileKey != fileKey {
+			if err := backend.Delete(ossBucket.Name, uploadedFileKey); err != nil {
+				log.Logger.Error("backend.Delete", zap.String("fileKey", uploadedFileKey), zap.Error(err))
+			}
 		}
+
+		return &File{
+			Id:             fileEntity.Id,
+			BucketId:       ossBucket.Id,
+			BucketName:     ossBucket.Name,
+			Domain:         ossBucket.Domain,
+			FileKey:        fileKey,
+			SourceFile:     uploadFile.SourceFile,
+			SourceFileSize: result.Size,
+			SourceFileType: uploadFile.SourceFileType,
+			SourceFileAttr: uploadFile.SourceFileAttr,
+			ContentType:    contentType,
+			Sha256:         result.Sha256,
+			Md5:            result.Md5,
+			Crc64:          result.Crc64,
+			Url:            getUrl(backend, *ossBucket, fileKey, uploadFile.ExpiredInSec, uploadFile.ProcessParams),
+			CreateTime:     now,
+			UpdateTime:     now,
+		}, nil
 	}
 }
 
@@ -135,10 +161,19 @@ func GetUploadToken(uploadFile OssUploadFile) (*OssUploadToken, error) {
 		return nil, err
 	} else if appEntity, err := repository.AppRepository.FindById(engine, ossBucket.AppId); err != nil || appEntity.Id == 0 {
 		return nil, err
-	} else if ossClient, err := getOssClientByBucket(*appEntity); err != nil {
+	} else if err := checkAccess(*appEntity, *ossBucket, accessWrite); err != nil {
+		return nil, err
+	} else if backend, err := getBackendByApp(*appEntity); err != nil {
 		return nil, err
 	} else {
 		fileKey := generateFileKey(uploadFile)
+		expiredInSec := int64(60 * 60)
+
+		if uploadFile.ExpiredInSec > 0 {
+			expiredInSec = uploadFile.ExpiredInSec
+		}
+
+		now := carbon.Now()
 
 		fileEntity := &repository.File{
 			BucketId:       ossBucket.Id,
@@ -147,58 +182,68 @@ func GetUploadToken(uploadFile OssUploadFile) (*OssUploadToken, error) {
 			SourceFileType: uploadFile.SourceFileType,
 			SourceFileSize: uploadFile.SourceFileSize,
 			SourceFileAttr: uploadFile.SourceFileAttr,
+			Status:         fileStatusPending,
+			UploadExpireAt: now.Timestamp() + expiredInSec,
+			CreateTime:     now.ToDateTimeString(),
+			UpdateTime:     now.ToDateTimeString(),
 		}
 
-		fileEntity.BucketId = ossBucket.Id
-		fileEntity.FileKey = fileKey
-		fileEntity.SourceFile = uploadFile.SourceFile
-		fileEntity.SourceFileSize = uploadFile.SourceFileSize
-
-		now := carbon.Now().ToDateTimeString()
-
-		fileEntity.CreateTime = now
-		fileEntity.UpdateTime = now
+		uploadConfig, err := mergeUploadConfig(parseUploadConfig(ossBucket.UploadConfig), uploadFile.UploadConfig)
 
-		if err := repository.FileRepository.Create(engine, fileEntity); err != nil || fileEntity.Id == 0 {
+		if err != nil {
+			return nil, err
+		} else if err := checkUploadFileSize(uploadConfig, uploadFile.SourceFileSize); err != nil {
 			return nil, err
 		}
 
-		bucket := ossBucket.Name
-		endpoint := appEntity.Endpoint
-		accessKeyId := appEntity.AccessKeyId
-		secretAccessKey := appEntity.AccessKeySecret
-		uploadUrl := fmt.Sprintf("//%s.%s", bucket, endpoint)
+		policyOptions := uploadConfigToPostPolicyOptions(uploadConfig, storage.PostPolicyOptions{Domain: ossBucket.Domain, ExpiredInSec: expiredInSec})
 
-		if ossBucket.Domain != "" {
-			uploadUrl = fmt.Sprintf("//%s", ossBucket.Domain)
+		if uploadFile.Callback == 1 {
+			if appEntity.Provider != "" && appEntity.Provider != storage.ProviderOss {
+				return nil, winter.NewBadRequestBusinessError("仅阿里云OSS支持上传回调")
+			} else if getOssCallbackUrl() == "" {
+				return nil, winter.NewBadRequestBusinessError("未配置上传回调地址")
+			} else if getOssCallbackSecret() == "" {
+				return nil, winter.NewBadRequestBusinessError("未配置上传回调秘钥")
+			}
 		}
 
-		expiredInSec := int64(60 * 60)
-		expiration := time.Now().Add(time.Duration(60 * time.Minute)).UTC().Format(time.RFC3339Nano)
+		putHeaders := make(map[string]string)
 
-		if uploadFile.ExpiredInSec > 0 {
-			expiredInSec = uploadFile.ExpiredInSec
-			expiration = time.Now().Add(time.Duration(uploadFile.ExpiredInSec) * time.Second).UTC().Format(time.RFC3339Nano)
+		if uploadFile.UploadMode == uploadModePut {
+			if putHeaders, err = getPutUploadHeaders(appEntity.Provider, uploadFile, uploadConfig); err != nil {
+				return nil, err
+			}
 		}
 
-		policyStr := fmt.Sprintf(`{"expiration":"%s","conditions":[{"bucket":"%s"},["eq","$key","%s"]]}`, expiration, bucket, fileKey)
+		if err := repository.FileRepository.Create(engine, fileEntity); err != nil || fileEntity.Id == 0 {
+			return nil, err
+		}
 
-		policy := base64.StdEncoding.EncodeToString([]byte(policyStr))
+		if uploadFile.Callback == 1 {
+			policyOptions.Callback = newOssCallback(fileEntity.Id, fileKey)
+		}
+
+		if uploadFile.UploadMode == uploadModePut {
+			return getPutUploadToken(backend, *ossBucket, fileEntity.Id, fileKey, putHeaders, policyOptions.Callback, expiredInSec, uploadFile.ProcessParams)
+		}
 
-		key := []byte(secretAccessKey)
-		mac := hmac.New(sha1.New, key)
-		mac.Write([]byte(policy))
+		policyToken, err := backend.PostPolicyToken(ossBucket.Name, fileKey, policyOptions)
 
-		signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		if err != nil {
+			return nil, err
+		}
 
 		return &OssUploadToken{
 			FileId:      fileEntity.Id,
-			UploadUrl:   uploadUrl,
-			AccessKeyId: accessKeyId,
-			Policy:      policy,
-			Signature:   signature,
+			Method:      http.MethodPost,
+			UploadUrl:   policyToken.UploadUrl,
+			AccessKeyId: policyToken.AccessKeyId,
+			Policy:      policyToken.Policy,
+			Signature:   policyToken.Signature,
 			Key:         fileKey,
-			Url:         getUrl(ossClient, *appEntity, *ossBucket, fileKey, expiredInSec, uploadFile.ProcessParams)}, nil
+			Fields:      policyToken.Fields,
+			Url:         getUrl(backend, *ossBucket, fileKey, expiredInSec, uploadFile.ProcessParams)}, nil
 	}
 }
 
@@ -214,7 +259,7 @@ func SearchFiles(searchParam SearchFileParam) ([]File, error) {
 	params := make([]any, 0, len(searchParam.Ids)+len(searchParam.FileKeys))
 
 	sb.WriteString("SELECT f.*,b.name AS bucket_name,b.domain FROM file f JOIN bucket b ON f.bucket_id=b.id JOIN app a ON b.app_id=a.id")
-	sb.WriteString(" WHERE f.del_status=0 AND b.del_status=0 AND a.del_status=0")
+	sb.WriteString(" WHERE f.del_status=0 AND f.status=0 AND b.del_status=0 AND a.del_status=0")
 
 	if len(searchParam.Ids) > 0 {
 		sb.WriteString(" AND f.id IN(")
@@ -282,8 +327,12 @@ func SearchFiles(searchParam SearchFileParam) ([]File, error) {
 }
 
 func SearchPageFiles(searchParam SearchFilePageParam) (winter.PageResult, error) {
+	return searchPageFiles(searchParam, 0)
+}
+
+func searchPageFiles(searchParam SearchFilePageParam, delStatus int) (winter.PageResult, error) {
 	engine := GetDB()
-	where, params := buildSearchFilesWhere(searchParam)
+	where, params := buildSearchFilesWhere(searchParam, delStatus)
 	countSb := new(strings.Builder)
 
 	countSb.WriteString("SELECT COUNT(f.id) FROM file f JOIN bucket b ON f.bucket_id=b.id JOIN app a ON b.app_id=a.id")
@@ -333,15 +382,199 @@ func SearchPageFiles(searchParam SearchFilePageParam) (winter.PageResult, error)
 	}
 }
 
-func getOssClientByBucket(appEntity repository.App) (*oss.Client, error) {
-	if v, ok := ossClientCache.Load(strconv.FormatInt(appEntity.Id, 10)); ok {
-		return v.(*oss.Client), nil
-	} else if ossClient, err := oss.New(appEntity.Endpoint, appEntity.AccessKeyId, appEntity.AccessKeySecret); err != nil {
+func DeleteFileById(id int64) (*DeleteFileResult, error) {
+	if fileEntity, err := repository.FileRepository.FindById(GetDB(), id); err != nil {
+		return nil, err
+	} else if fileEntity.Id == 0 {
+		return nil, winter.NewNotFoundBusinessError("文件不存在")
+	} else if results, err := DeleteFiles(DeleteFileParam{Ids: []int64{id}}); err != nil {
 		return nil, err
 	} else {
-		ossClientCache.Store(strconv.FormatInt(appEntity.Id, 10), ossClient)
+		return &results[0], nil
+	}
+}
+
+func DeleteFiles(deleteParam DeleteFileParam) ([]DeleteFileResult, error) {
+	engine := GetDB()
+	results := make([]DeleteFileResult, 0, len(deleteParam.Ids)+len(deleteParam.FileKeys))
+	entities := make([]repository.File, 0, len(deleteParam.Ids)+len(deleteParam.FileKeys))
+
+	if len(deleteParam.Ids) > 0 {
+		if ms, err := repository.FileRepository.FindByIdIn(engine, deleteParam.Ids); err != nil {
+			return nil, err
+		} else {
+			idMap := make(map[int64]bool)
+
+			for _, m := range ms {
+				idMap[m.Id] = true
+			}
 
-		return ossClient, nil
+			for _, id := range deleteParam.Ids {
+				if !idMap[id] {
+					results = append(results, DeleteFileResult{Id: id, Message: "文件不存在"})
+				}
+			}
+
+			entities = append(entities, ms...)
+		}
+	}
+
+	if len(deleteParam.FileKeys) > 0 {
+		if bucketEntity, err := repository.BucketRepository.FindByName(engine, deleteParam.Bucket); err != nil {
+			return nil, err
+		} else if bucketEntity.Id == 0 {
+			return nil, winter.NewBadRequestBusinessError("存储空间不存在")
+		} else if ms, err := repository.FileRepository.FindByBucketIdAndFileKeyIn(engine, bucketEntity.Id, deleteParam.FileKeys); err != nil {
+			return nil, err
+		} else {
+			// 去重或覆盖上传后同一文件key可能对应多个文件，按文件key删除无法确定删除哪一个，需按ID删除
+			fileKeyMap := make(map[string]int)
+
+			for _, m := range ms {
+				fileKeyMap[m.FileKey]++
+			}
+
+			for _, fileKey := range deleteParam.FileKeys {
+				if fileKeyMap[fileKey] == 0 {
+					results = append(results, DeleteFileResult{FileKey: fileKey, Message: "文件不存在"})
+				} else if fileKeyMap[fileKey] > 1 {
+					results = append(results, DeleteFileResult{FileKey: fileKey, Message: "文件key被多个文件引用，请按ID删除"})
+				}
+			}
+
+			for _, m := range ms {
+				if fileKeyMap[m.FileKey] == 1 {
+					entities = append(entities, m)
+				}
+			}
+		}
+	}
+
+	if len(entities) == 0 {
+		return results, nil
+	}
+
+	bucketIds := make([]int64, 0, len(entities))
+
+	for _, entity := range entities {
+		bucketIds = append(bucketIds, entity.BucketId)
+	}
+
+	bucketMap, err := getBucketMap(engine, bucketIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	appIds := make([]int64, 0, len(bucketMap))
+
+	for _, bucketEntity := range bucketMap {
+		appIds = append(appIds, bucketEntity.AppId)
+	}
+
+	appMap, err := getAppMap(engine, appIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	deletedIdMap := make(map[int64]bool)
+
+	for _, entity := range entities {
+		if deletedIdMap[entity.Id] {
+			continue
+		}
+
+		deletedIdMap[entity.Id] = true
+
+		results = append(results, deleteFile(engine, entity, bucketMap, appMap))
+	}
+
+	return results, nil
+}
+
+func deleteFile(engine *xorm.Engine, entity repository.File, bucketMap map[int64]repository.Bucket, appMap map[int64]repository.App) DeleteFileResult {
+	result := DeleteFileResult{Id: entity.Id, FileKey: entity.FileKey}
+
+	bucketEntity, ok := bucketMap[entity.BucketId]
+
+	if !ok {
+		result.Message = "存储空间不存在"
+
+		return result
+	}
+
+	appEntity, ok := appMap[bucketEntity.AppId]
+
+	if !ok {
+		result.Message = "应用不存在"
+
+		return result
+	} else if err := checkAccess(appEntity, bucketEntity, accessWrite); err != nil {
+		result.Message = err.Error()
+
+		return result
+	}
+
+	if bucketEntity.TrashRetentionDays > 0 {
+		if _, err := repository.FileRepository.DeleteById(engine, entity.Id); err != nil {
+			result.Message = err.Error()
+
+			return result
+		}
+	} else if err := purgeFile(engine, entity, bucketEntity, appEntity); err != nil {
+		result.Message = err.Error()
+
+		return result
+	}
+
+	result.Success = true
+
+	return result
+}
+
+// 先清除文件数据再删除对象：对象删除失败只会遗留无引用的对象，不会出现有效文件指向已删除的对象
+func purgeFile(engine *xorm.Engine, entity repository.File, bucketEntity repository.Bucket, appEntity repository.App) error {
+	if affected, err := repository.FileRepository.PurgeById(engine, entity.Id, entity.DelStatus); err != nil {
+		return err
+	} else if affected == 0 {
+		return winter.NewNotFoundBusinessError("文件不存在")
+	}
+
+	if deletable, err := releaseFileObject(engine, entity, bucketEntity); err != nil {
+		log.Logger.Error("releaseFileObject", zap.Int64("fileId", entity.Id), zap.Error(err))
+	} else if !deletable {
+		return nil
+	} else if backend, err := getBackendByApp(appEntity); err != nil {
+		log.Logger.Error("getBackendByApp", zap.Int64("appId", appEntity.Id), zap.Error(err))
+	} else if err := backend.Delete(bucketEntity.Name, entity.FileKey); err != nil {
+		log.Logger.Error("backend.Delete", zap.String("bucketName", bucketEntity.Name), zap.String("fileKey", entity.FileKey), zap.Error(err))
+	}
+
+	return nil
+}
+
+func getBackendByBucketName(engine *xorm.Engine, bucketName string, mode accessMode) (*repository.Bucket, storage.Backend, error) {
+	if bucketEntity, err := repository.BucketRepository.FindByName(engine, bucketName); err != nil {
+		return nil, nil, err
+	} else if bucketEntity.Id == 0 {
+		return nil, nil, winter.NewBadRequestBusinessError("存储空间不存在")
+	} else if backend, err := getBackendByBucket(engine, *bucketEntity, mode); err != nil {
+		return nil, nil, err
+	} else {
+		return bucketEntity, backend, nil
+	}
+}
+
+func getBackendByBucket(engine *xorm.Engine, bucketEntity repository.Bucket, mode accessMode) (storage.Backend, error) {
+	if appEntity, err := repository.AppRepository.FindById(engine, bucketEntity.AppId); err != nil {
+		return nil, err
+	} else if appEntity.Id == 0 {
+		return nil, winter.NewBadRequestBusinessError("应用不存在")
+	} else if err := checkAccess(*appEntity, bucketEntity, mode); err != nil {
+		return nil, err
+	} else {
+		return getBackendByApp(*appEntity)
 	}
 }
 
@@ -368,184 +601,103 @@ func generateFileKey(uploadFile OssUploadFile) string {
 	return sb.String()
 }
 
-func getUrl(ossClient *oss.Client, app repository.App, bucket repository.Bucket, fileKey string, expiredInSec int64, processParams []ProcessParam) string {
-	sb := new(strings.Builder)
+func getUrl(backend storage.Backend, bucket repository.Bucket, fileKey string, expiredInSec int64, processParams []ProcessParam) string {
+	process := getProcess(bucket, fileKey, processParams)
 
 	if bucket.BucketType == 1 {
+		sb := new(strings.Builder)
+
 		sb.WriteString("//")
 		sb.WriteString(bucket.Domain)
 		sb.WriteString("/")
 		sb.WriteString(fileKey)
 
-		if len(processParams) > 0 {
+		if process != "" {
 			sb.WriteString("?x-oss-process=")
+			sb.WriteString(process)
+		}
 
-			for i, porcessParam := range processParams {
-				sb.WriteString(porcessParam.Name)
-
-				if len(porcessParam.Params) > 0 {
-					sb.WriteString(",")
+		return sb.String()
+	} else if bucket.BucketType == 2 {
+		signOptions := storage.SignOptions{
+			ExpiredInSec: expiredInSec,
+			Process:      process,
+			Domain:       bucket.Domain,
+		}
 
-					for i, param := range porcessParam.Params {
-						sb.WriteString(param)
+		if signedURL, err := backend.SignURL(bucket.Name, fileKey, signOptions); err != nil {
+			log.Logger.Error("backend.SignURL", zap.String("bucketName", bucket.Name), zap.String("fileKey", fileKey), zap.Error(err))
+		} else {
+			return signedURL
+		}
+	}
 
-						if i < len(porcessParam.Params)-1 {
-							sb.WriteString(",")
-						}
-					}
-				}
+	return ""
+}
 
-				if i < len(processParams)-1 {
-					sb.WriteString("/")
-				}
-			}
-		} else if len(processParams) == 0 && bucket.ProcessConfig != "" {
-			processConfig := &ProcessConfig{}
-
-			if err := json.Unmarshal(([]byte)(bucket.ProcessConfig), &processConfig); err != nil {
-				log.Logger.Error("解析ProcessConfig失败", zap.Any("ProcessConfig", bucket.ProcessConfig), zap.Error(err))
-			} else if processConfig.Expression != "" {
-				if expression, err := govaluate.NewEvaluableExpressionWithFunctions(processConfig.Expression, functions); err != nil {
-					log.Logger.Error("解析ProcessConfig.Expression失败", zap.Any("ProcessConfig.Expression", processConfig.Expression), zap.Error(err))
-				} else {
-					parameters := make(map[string]any)
-
-					parameters["bucket"] = bucket.Name
-					parameters["fileKey"] = fileKey
-					parameters["fileType"] = filepath.Ext(fileKey)
-
-					if result, err := expression.Evaluate(parameters); err != nil {
-						log.Logger.Error("执行ProcessConfig.Expression失败", zap.Any("ProcessConfig.Expression", processConfig.Expression), zap.Error(err))
-					} else {
-						if result, ok := result.(bool); ok && result {
-							if len(processConfig.ProcessParams) > 0 {
-								sb.WriteString("?x-oss-process=")
-
-								for i, porcessParam := range processConfig.ProcessParams {
-									sb.WriteString(porcessParam.Name)
-
-									if len(porcessParam.Params) > 0 {
-										sb.WriteString(",")
-
-										for i, param := range porcessParam.Params {
-											sb.WriteString(param)
-
-											if i < len(porcessParam.Params)-1 {
-												sb.WriteString(",")
-											}
-										}
-									}
-
-									if i < len(processConfig.ProcessParams)-1 {
-										sb.WriteString("/")
-									}
-								}
-							}
-						}
-					}
+func getProcess(bucket repository.Bucket, fileKey string, processParams []ProcessParam) string {
+	if len(processParams) > 0 {
+		return buildProcess(processParams)
+	} else if bucket.ProcessConfig != "" {
+		processConfig := &ProcessConfig{}
+
+		if err := json.Unmarshal(([]byte)(bucket.ProcessConfig), &processConfig); err != nil {
+			log.Logger.Error("解析ProcessConfig失败", zap.Any("ProcessConfig", bucket.ProcessConfig), zap.Error(err))
+		} else if processConfig.Expression != "" {
+			if expression, err := govaluate.NewEvaluableExpressionWithFunctions(processConfig.Expression, functions); err != nil {
+				log.Logger.Error("解析ProcessConfig.Expression失败", zap.Any("ProcessConfig.Expression", processConfig.Expression), zap.Error(err))
+			} else {
+				parameters := make(map[string]any)
+
+				parameters["bucket"] = bucket.Name
+				parameters["fileKey"] = fileKey
+				parameters["fileType"] = filepath.Ext(fileKey)
+
+				if result, err := expression.Evaluate(parameters); err != nil {
+					log.Logger.Error("执行ProcessConfig.Expression失败", zap.Any("ProcessConfig.Expression", processConfig.Expression), zap.Error(err))
+				} else if result, ok := result.(bool); ok && result && len(processConfig.ProcessParams) > 0 {
+					return buildProcess(processConfig.ProcessParams)
 				}
 			}
-
 		}
-	} else if bucket.BucketType == 2 {
-		ossBucket, _ := ossClient.Bucket(bucket.Name)
-
-		options := make([]oss.Option, 0, len(processParams))
+	}
 
-		if len(processParams) > 0 {
-			processParamSb := new(strings.Builder)
+	return ""
+}
 
-			for i, porcessParam := range processParams {
-				processParamSb.WriteString(porcessParam.Name)
+func buildProcess(processParams []ProcessParam) string {
+	sb := new(strings.Builder)
 
-				if len(porcessParam.Params) > 0 {
-					processParamSb.WriteString(",")
+	for i, porcessParam := range processParams {
+		sb.WriteString(porcessParam.Name)
 
-					for i, param := range porcessParam.Params {
-						processParamSb.WriteString(param)
+		if len(porcessParam.Params) > 0 {
+			sb.WriteString(",")
 
-						if i < len(porcessParam.Params)-1 {
-							processParamSb.WriteString(",")
-						}
-					}
-				}
+			for i, param := range porcessParam.Params {
+				sb.WriteString(param)
 
-				if i < len(processParams)-1 {
-					processParamSb.WriteString("/")
-				}
-			}
-
-			options = append(options, oss.Process(processParamSb.String()))
-		} else if len(processParams) == 0 && bucket.ProcessConfig != "" {
-			processConfig := &ProcessConfig{}
-
-			if err := json.Unmarshal(([]byte)(bucket.ProcessConfig), &processConfig); err != nil {
-				log.Logger.Error("解析ProcessConfig失败", zap.Any("ProcessConfig", bucket.ProcessConfig), zap.Error(err))
-			} else if processConfig.Expression != "" {
-				if expression, err := govaluate.NewEvaluableExpressionWithFunctions(processConfig.Expression, functions); err != nil {
-					log.Logger.Error("解析ProcessConfig.Expression失败", zap.Any("ProcessConfig.Expression", processConfig.Expression), zap.Error(err))
-				} else {
-					parameters := make(map[string]any)
-
-					parameters["bucket"] = bucket.Name
-					parameters["fileKey"] = fileKey
-					parameters["fileType"] = filepath.Ext(fileKey)
-
-					if result, err := expression.Evaluate(parameters); err != nil {
-						log.Logger.Error("执行ProcessConfig.Expression失败", zap.Any("ProcessConfig.Expression", processConfig.Expression), zap.Error(err))
-					} else {
-						if result, ok := result.(bool); ok && result {
-							if len(processConfig.ProcessParams) > 0 {
-								processParamSb := new(strings.Builder)
-
-								for i, porcessParam := range processConfig.ProcessParams {
-									processParamSb.WriteString(porcessParam.Name)
-
-									if len(porcessParam.Params) > 0 {
-										processParamSb.WriteString(",")
-
-										for i, param := range porcessParam.Params {
-											processParamSb.WriteString(param)
-
-											if i < len(porcessParam.Params)-1 {
-												processParamSb.WriteString(",")
-											}
-										}
-									}
-
-									if i < len(processConfig.ProcessParams)-1 {
-										processParamSb.WriteString("/")
-									}
-								}
-
-								options = append(options, oss.Process(processParamSb.String()))
-							}
-						}
-					}
+				if i < len(porcessParam.Params)-1 {
+					sb.WriteString(",")
 				}
 			}
 		}
 
-		signedURL, err := ossBucket.SignURL(fileKey, oss.HTTPGet, expiredInSec, options...)
-
-		fmt.Println(signedURL)
-
-		if err == nil && signedURL != "" && strings.Contains(signedURL, "//") {
-			str := signedURL[strings.Index(signedURL, "//"):]
-
-			return strings.Replace(str, fmt.Sprintf("%s.%s", bucket.Name, app.Endpoint), bucket.Domain, 1)
+		if i < len(processParams)-1 {
+			sb.WriteString("/")
 		}
-
 	}
 
 	return sb.String()
 }
 
-func buildSearchFilesWhere(searchParam SearchFilePageParam) (string, []any) {
+func buildSearchFilesWhere(searchParam SearchFilePageParam, delStatus int) (string, []any) {
 	sb := new(strings.Builder)
-	params := make([]any, 0, len(searchParam.Ids)+len(searchParam.FileKeys)+2)
+	params := make([]any, 0, len(searchParam.Ids)+len(searchParam.FileKeys)+3)
 
-	sb.WriteString(" WHERE f.del_status=0 AND b.del_status=0 AND a.del_status=0")
+	sb.WriteString(" WHERE f.del_status=? AND f.status=0 AND b.del_status=0 AND a.del_status=0")
+
+	params = append(params, delStatus)
 
 	if len(searchParam.Ids) > 0 {
 		sb.WriteString(" AND f.id IN(")
@@ -637,9 +789,9 @@ func mergeFiles(engine *xorm.Engine, files *[]File, expiredInSec int64, processP
 
 	for i, file := range *files {
 		if bucketEntity, ok := bucketMap[file.BucketId]; ok {
-			if appEntity, ok := appMap[bucketEntity.AppId]; ok {
-				if ossClient, err := getOssClientByBucket(appEntity); err == nil {
-					(*files)[i].Url = getUrl(ossClient, appEntity, bucketEntity, file.FileKey, expiredInSec, processParams)
+			if appEntity, ok := appMap[bucketEntity.AppId]; ok && checkAccess(appEntity, bucketEntity, accessRead) == nil {
+				if backend, err := getBackendByApp(appEntity); err == nil {
+					(*files)[i].Url = getUrl(backend, bucketEntity, file.FileKey, expiredInSec, processParams)
 				}
 			}
 		}
@@ -678,31 +830,15 @@ func getAppMap(engine *xorm.Engine, ids []int64) (map[int64]repository.App, erro
 	}
 }
 
-func ossUploadFile(bucket *oss.Bucket, fileKey string, file string) error {
-	var err error
-	retryCount := 0
-	retryMax := 3
-
-	if err = bucket.UploadFile(fileKey, file, 100*1024, oss.Routines(3), oss.Checkpoint(true, "")); err != nil {
-		log.Logger.Error("bucket.UploadFile", zap.String("fileKey", fileKey), zap.String("file", file), zap.Error(err))
-
-		for retryCount < retryMax && err != nil {
-			if err = bucket.UploadFile(fileKey, file, 100*1024, oss.Routines(3), oss.Checkpoint(true, "")); err != nil {
-				log.Logger.Error("bucket.UploadFile", zap.String("fileKey", fileKey), zap.String("file", file), zap.Error(err))
-			}
-
-			retryCount++
-		}
-	}
-
-	return err
-}
-
 func CreateFileData(file File) ([]File, error) {
 
 	ms := make([]File, 0)
 	engine := GetDB()
 
+	if err := checkBucketAccessById(engine, file.BucketId, accessWrite); err != nil {
+		return nil, err
+	}
+
 	fileEntity := &repository.File{
 		BucketId:       file.BucketId,
 		FileKey:        file.FileKey,
@@ -756,6 +892,9 @@ func BatchCreateFile(files []File) ([]File, error) {
 	engine := GetDB()
 
 	for _, file := range files {
+		if err := checkBucketAccessById(engine, file.BucketId, accessWrite); err != nil {
+			return nil, err
+		}
 
 		msd := make([]File, 0)
 
@@ -0,0 +1,489 @@
+package object
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dromara/carbon/v2"
+	"github.com/easynet-cn/file-service/log"
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/storage"
+	"github.com/easynet-cn/winter"
+	"go.uber.org/zap"
+	"xorm.io/xorm"
+)
+
+const (
+	fileAuditBatchSize             = 500
+	fileAuditStaleMinutes          = 60
+	fileAuditScheduleSkipHours     = 23
+	fileAuditLockId                = 1
+	fileAuditLockInitTime          = "2000-01-01 00:00:00"
+	fileAuditStatusRunning         = 0
+	fileAuditStatusCompleted       = 1
+	fileAuditStatusFailed          = 2
+	fileAuditTriggerScheduled      = 0
+	fileAuditTriggerManual         = 1
+	fileAuditIssueMissing          = 1
+	fileAuditIssueSizeMismatch     = 2
+	fileAuditIssueChecksumMismatch = 3
+	fileAuditIssueHeadFailed       = 4
+)
+
+var (
+	errFileAuditScheduled   = errors.New("其他实例已执行定时巡检")
+	errFileAuditLockLost    = errors.New("巡检执行超时，已由其他巡检接管")
+	fileAuditIssueTypeNames = map[int]string{
+		fileAuditIssueMissing:          "对象不存在",
+		fileAuditIssueSizeMismatch:     "大小不一致",
+		fileAuditIssueChecksumMismatch: "校验值不一致",
+		fileAuditIssueHeadFailed:       "查询失败",
+	}
+)
+
+type FileAuditParam struct {
+	Bucket string `json:"bucket" form:"bucket"` //bucket名称，为空时巡检全部存储空间
+}
+
+type FileAuditIssuePageParam struct {
+	winter.PageParam
+	AuditId   int64 `json:"auditId"`   //巡检ID
+	IssueType int   `json:"issueType"` //异常类型，0：全部
+}
+
+// 定时巡检全部存储空间，每个实例都会触发，已有执行中的巡检或其他实例近期已执行定时巡检时跳过
+func RunFileAudit() {
+	engine := GetDB()
+
+	if auditEntity, err := createFileAudit(engine, 0, fileAuditTriggerScheduled); errors.Is(err, errFileAuditScheduled) {
+		log.Logger.Info("跳过定时文件巡检", zap.Error(err))
+	} else if err != nil {
+		log.Logger.Warn("创建文件巡检失败", zap.Error(err))
+	} else if bucketEntities, err := repository.BucketRepository.FindAll(engine); err != nil {
+		finishFileAudit(engine, auditEntity, err)
+	} else {
+		runFileAudit(engine, auditEntity, bucketEntities)
+	}
+}
+
+func StartFileAudit(param FileAuditParam) (*repository.FileAudit, error) {
+	engine := GetDB()
+	bucketId := int64(0)
+	bucketEntities := make([]repository.Bucket, 0)
+
+	if param.Bucket != "" {
+		if bucketEntity, err := repository.BucketRepository.FindByName(engine, param.Bucket); err != nil {
+			return nil, err
+		} else if bucketEntity.Id == 0 {
+			return nil, winter.NewBadRequestBusinessError("存储空间不存在")
+		} else {
+			bucketId = bucketEntity.Id
+			bucketEntities = append(bucketEntities, *bucketEntity)
+		}
+	} else if ms, err := repository.BucketRepository.FindAll(engine); err != nil {
+		return nil, err
+	} else {
+		bucketEntities = ms
+	}
+
+	auditEntity, err := createFileAudit(engine, bucketId, fileAuditTriggerManual)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := *auditEntity
+
+	go runFileAudit(engine, auditEntity, bucketEntities)
+
+	return &result, nil
+}
+
+func GetFileAudit(id int64) (*repository.FileAudit, error) {
+	if auditEntity, err := repository.FileAuditRepository.FindById(GetDB(), id); err != nil {
+		return nil, err
+	} else if auditEntity.Id == 0 {
+		return nil, winter.NewNotFoundBusinessError("文件巡检不存在")
+	} else {
+		return auditEntity, nil
+	}
+}
+
+func SearchPageFileAudits(searchParam winter.PageParam) (winter.PageResult, error) {
+	engine := GetDB()
+	total := int64(0)
+
+	if _, err := engine.SQL("SELECT COUNT(id) FROM file_audit").Get(&total); err != nil {
+		return *winter.NewPageResult(), err
+	}
+
+	if total > 0 {
+		ms := make([]repository.FileAudit, 0)
+
+		if err := engine.SQL("SELECT * FROM file_audit ORDER BY id DESC LIMIT ?,?", searchParam.Start(), searchParam.PageSize).Find(&ms); err != nil {
+			return *winter.NewPageResult(), err
+		}
+
+		pageResult := &winter.PageResult{Total: total, Data: make([]any, len(ms))}
+
+		pageResult.TotalPages = pageResult.GetTotalPages(searchParam.PageSize)
+
+		for i, m := range ms {
+			pageResult.Data[i] = m
+		}
+
+		return *pageResult, nil
+	} else {
+		return winter.PageResult{Data: make([]any, 0)}, nil
+	}
+}
+
+func SearchPageFileAuditIssues(searchParam FileAuditIssuePageParam) (winter.PageResult, error) {
+	engine := GetDB()
+	where := " WHERE audit_id=?"
+	params := []any{searchParam.AuditId}
+
+	if searchParam.IssueType > 0 {
+		where += " AND issue_type=?"
+		params = append(params, searchParam.IssueType)
+	}
+
+	total := int64(0)
+
+	if _, err := engine.SQL("SELECT COUNT(id) FROM file_audit_issue"+where, params...).Get(&total); err != nil {
+		return *winter.NewPageResult(), err
+	}
+
+	if total > 0 {
+		ms := make([]repository.FileAuditIssue, 0)
+		queryParams := append(params, searchParam.Start(), searchParam.PageSize)
+
+		if err := engine.SQL("SELECT * FROM file_audit_issue"+where+" ORDER BY id LIMIT ?,?", queryParams...).Find(&ms); err != nil {
+			return *winter.NewPageResult(), err
+		}
+
+		pageResult := &winter.PageResult{Total: total, Data: make([]any, len(ms))}
+
+		pageResult.TotalPages = pageResult.GetTotalPages(searchParam.PageSize)
+
+		for i, m := range ms {
+			pageResult.Data[i] = m
+		}
+
+		return *pageResult, nil
+	} else {
+		return winter.PageResult{Data: make([]any, 0)}, nil
+	}
+}
+
+// 以CSV格式导出巡检异常，写入UTF-8 BOM以便Excel正确识别中文
+func ExportFileAuditIssues(auditId int64, writer io.Writer) error {
+	engine := GetDB()
+
+	if _, err := writer.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return err
+	}
+
+	csvWriter := csv.NewWriter(writer)
+
+	if err := csvWriter.Write([]string{"文件ID", "空间ID", "文件键值", "异常类型", "期望值", "实际值", "发现时间"}); err != nil {
+		return err
+	}
+
+	lastId := int64(0)
+
+	for {
+		issueEntities, err := repository.FileAuditIssueRepository.FindByAuditIdAndIdAfter(engine, auditId, lastId, fileAuditBatchSize)
+
+		if err != nil {
+			return err
+		}
+
+		for _, issueEntity := range issueEntities {
+			record := []string{
+				strconv.FormatInt(issueEntity.FileId, 10),
+				strconv.FormatInt(issueEntity.BucketId, 10),
+				issueEntity.FileKey,
+				fileAuditIssueTypeNames[issueEntity.IssueType],
+				issueEntity.ExpectedValue,
+				issueEntity.ActualValue,
+				issueEntity.CreateTime,
+			}
+
+			if err := csvWriter.Write(record); err != nil {
+				return err
+			}
+
+			lastId = issueEntity.Id
+		}
+
+		csvWriter.Flush()
+
+		if err := csvWriter.Error(); err != nil {
+			return err
+		}
+
+		if len(issueEntities) < fileAuditBatchSize {
+			return nil
+		}
+	}
+}
+
+// 先检查巡检锁，创建巡检后再以条件更新抢占，抢占失败时删除刚创建的巡检
+func createFileAudit(engine *xorm.Engine, bucketId int64, triggerType int) (*repository.FileAudit, error) {
+	lockEntity, err := findFileAuditLock(engine, triggerType)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := carbon.Now().ToDateTimeString()
+
+	auditEntity := &repository.FileAudit{
+		BucketId:    bucketId,
+		TriggerType: triggerType,
+		Status:      fileAuditStatusRunning,
+		StartTime:   now,
+		EndTime:     now,
+		CreateTime:  now,
+		UpdateTime:  now,
+	}
+
+	if err := repository.FileAuditRepository.Create(engine, auditEntity); err != nil {
+		return nil, err
+	}
+
+	if err := acquireFileAuditLock(engine, lockEntity, auditEntity); err != nil {
+		if _, err := repository.FileAuditRepository.DeleteById(engine, auditEntity.Id); err != nil {
+			log.Logger.Error("repository.DeleteFileAudit", zap.Int64("auditId", auditEntity.Id), zap.Error(err))
+		}
+
+		return nil, err
+	}
+
+	return auditEntity, nil
+}
+
+// 巡检锁只有一行，不存在时创建，多个实例同时创建时主键冲突，重新读取
+func findFileAuditLock(engine *xorm.Engine, triggerType int) (*repository.FileAuditLock, error) {
+	lockEntity, err := repository.FileAuditLockRepository.FindById(engine, fileAuditLockId)
+
+	if err != nil {
+		return nil, err
+	} else if lockEntity.Id == 0 {
+		lockEntity = &repository.FileAuditLock{Id: fileAuditLockId, ScheduleTime: fileAuditLockInitTime, UpdateTime: carbon.Now().ToDateTimeString()}
+
+		if err := repository.FileAuditLockRepository.Create(engine, lockEntity); err != nil {
+			if lockEntity, err = repository.FileAuditLockRepository.FindById(engine, fileAuditLockId); err != nil {
+				return nil, err
+			} else if lockEntity.Id == 0 {
+				return nil, errors.New("创建文件巡检锁失败")
+			}
+		}
+	}
+
+	lockEntity.UpdateTime = carbon.Parse(lockEntity.UpdateTime).ToDateTimeString()
+	lockEntity.ScheduleTime = carbon.Parse(lockEntity.ScheduleTime).ToDateTimeString()
+
+	if triggerType == fileAuditTriggerScheduled && lockEntity.ScheduleTime >= carbon.Now().SubHours(fileAuditScheduleSkipHours).ToDateTimeString() {
+		return nil, errFileAuditScheduled
+	} else if lockEntity.AuditId > 0 && lockEntity.UpdateTime >= carbon.Now().SubMinutes(fileAuditStaleMinutes).ToDateTimeString() {
+		return nil, winter.NewBusinessError(http.StatusConflict, "409", fmt.Sprintf("已有正在执行的文件巡检：%d", lockEntity.AuditId))
+	}
+
+	return lockEntity, nil
+}
+
+// 锁在读取后被其他实例抢占时更新行数为0；抢占的是超时的巡检时将其标记为失败
+func acquireFileAuditLock(engine *xorm.Engine, lockEntity *repository.FileAuditLock, auditEntity *repository.FileAudit) error {
+	staleAuditId, updateTime := lockEntity.AuditId, lockEntity.UpdateTime
+	cols := []string{"audit_id", "update_time"}
+
+	lockEntity.AuditId = auditEntity.Id
+	lockEntity.UpdateTime = auditEntity.StartTime
+
+	if auditEntity.TriggerType == fileAuditTriggerScheduled {
+		lockEntity.ScheduleTime = auditEntity.StartTime
+		cols = append(cols, "schedule_time")
+	}
+
+	if affected, err := repository.FileAuditLockRepository.UpdateByAuditIdAndUpdateTime(engine, cols, lockEntity, staleAuditId, updateTime); err != nil {
+		return err
+	} else if affected == 0 {
+		return winter.NewBusinessError(http.StatusConflict, "409", "文件巡检已由其他实例启动")
+	}
+
+	if staleAuditId > 0 {
+		if staleEntity, err := repository.FileAuditRepository.FindById(engine, staleAuditId); err != nil {
+			log.Logger.Error("repository.FindFileAudit", zap.Int64("auditId", staleAuditId), zap.Error(err))
+		} else if staleEntity.Id > 0 && staleEntity.Status == fileAuditStatusRunning {
+			finishFileAudit(engine, staleEntity, errors.New("巡检执行超时"))
+		}
+	}
+
+	return nil
+}
+
+// 刷新巡检锁的心跳，锁已被其他巡检抢占时停止当前巡检
+func refreshFileAuditLock(engine *xorm.Engine, auditEntity *repository.FileAudit) error {
+	lockEntity := &repository.FileAuditLock{Id: fileAuditLockId, UpdateTime: auditEntity.UpdateTime}
+
+	if affected, err := repository.FileAuditLockRepository.UpdateByAuditId(engine, []string{"update_time"}, lockEntity, auditEntity.Id); err != nil {
+		return err
+	} else if affected == 0 {
+		return errFileAuditLockLost
+	}
+
+	return nil
+}
+
+func releaseFileAuditLock(engine *xorm.Engine, auditEntity *repository.FileAudit) {
+	lockEntity := &repository.FileAuditLock{Id: fileAuditLockId, AuditId: 0, UpdateTime: auditEntity.UpdateTime}
+
+	if _, err := repository.FileAuditLockRepository.UpdateByAuditId(engine, []string{"audit_id", "update_time"}, lockEntity, auditEntity.Id); err != nil {
+		log.Logger.Error("repository.UpdateFileAuditLock", zap.Int64("auditId", auditEntity.Id), zap.Error(err))
+	}
+}
+
+func runFileAudit(engine *xorm.Engine, auditEntity *repository.FileAudit, bucketEntities []repository.Bucket) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Logger.Error("文件巡检异常", zap.Int64("auditId", auditEntity.Id), zap.Any("panic", r))
+
+			finishFileAudit(engine, auditEntity, fmt.Errorf("%v", r))
+		}
+	}()
+
+	messages := make([]string, 0)
+
+	for _, bucketEntity := range bucketEntities {
+		if err := auditBucketFiles(engine, auditEntity, bucketEntity); err != nil {
+			log.Logger.Error("auditBucketFiles", zap.Int64("auditId", auditEntity.Id), zap.String("bucketName", bucketEntity.Name), zap.Error(err))
+
+			messages = append(messages, fmt.Sprintf("%s：%v", bucketEntity.Name, err))
+
+			if errors.Is(err, errFileAuditLockLost) {
+				break
+			}
+		}
+	}
+
+	if len(messages) > 0 {
+		finishFileAudit(engine, auditEntity, errors.New(strings.Join(messages, "；")))
+	} else {
+		finishFileAudit(engine, auditEntity, nil)
+	}
+}
+
+func auditBucketFiles(engine *xorm.Engine, auditEntity *repository.FileAudit, bucketEntity repository.Bucket) error {
+	backend, err := getBackendByBucket(engine, bucketEntity, accessRead)
+
+	if err != nil {
+		return err
+	}
+
+	lastId := int64(0)
+
+	for {
+		fileEntities, err := repository.FileRepository.FindActiveByBucketIdAndIdAfter(engine, bucketEntity.Id, lastId, fileAuditBatchSize)
+
+		if err != nil {
+			return err
+		}
+
+		issueEntities := make([]repository.FileAuditIssue, 0)
+
+		for _, fileEntity := range fileEntities {
+			if issueEntity := checkFileObject(backend, bucketEntity.Name, fileEntity); issueEntity != nil {
+				issueEntity.AuditId = auditEntity.Id
+				issueEntity.CreateTime = carbon.Now().ToDateTimeString()
+
+				issueEntities = append(issueEntities, *issueEntity)
+			}
+
+			lastId = fileEntity.Id
+		}
+
+		if len(issueEntities) > 0 {
+			if err := repository.FileAuditIssueRepository.CreateBatch(engine, issueEntities); err != nil {
+				return err
+			}
+		}
+
+		auditEntity.CheckedCount += int64(len(fileEntities))
+		auditEntity.IssueCount += int64(len(issueEntities))
+		auditEntity.UpdateTime = carbon.Now().ToDateTimeString()
+
+		if err := repository.FileAuditRepository.Update(engine, []string{"checked_count", "issue_count", "update_time"}, auditEntity); err != nil {
+			return err
+		} else if err := refreshFileAuditLock(engine, auditEntity); err != nil {
+			return err
+		}
+
+		if len(fileEntities) < fileAuditBatchSize {
+			return nil
+		}
+	}
+}
+
+func finishFileAudit(engine *xorm.Engine, auditEntity *repository.FileAudit, err error) {
+	now := carbon.Now().ToDateTimeString()
+
+	auditEntity.Status = fileAuditStatusCompleted
+	auditEntity.EndTime = now
+	auditEntity.UpdateTime = now
+
+	if err != nil {
+		auditEntity.Status = fileAuditStatusFailed
+		auditEntity.Message = truncateString(err.Error(), 1000)
+	}
+
+	releaseFileAuditLock(engine, auditEntity)
+
+	if err := repository.FileAuditRepository.Update(engine, []string{"status", "message", "end_time", "update_time"}, auditEntity); err != nil {
+		log.Logger.Error("repository.UpdateFileAudit", zap.Int64("auditId", auditEntity.Id), zap.Error(err))
+	} else {
+		log.Logger.Info("文件巡检结束", zap.Int64("auditId", auditEntity.Id), zap.Int64("checkedCount", auditEntity.CheckedCount), zap.Int64("issueCount", auditEntity.IssueCount))
+	}
+}
+
+func checkFileObject(backend storage.Backend, bucketName string, fileEntity repository.File) *repository.FileAuditIssue {
+	issueEntity := &repository.FileAuditIssue{BucketId: fileEntity.BucketId, FileId: fileEntity.Id, FileKey: fileEntity.FileKey}
+	objectInfo, err := backend.Head(bucketName, fileEntity.FileKey)
+
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		issueEntity.IssueType = fileAuditIssueMissing
+	} else if err != nil {
+		issueEntity.IssueType = fileAuditIssueHeadFailed
+		issueEntity.ActualValue = truncateString(err.Error(), 1000)
+	} else if fileEntity.SourceFileSize > 0 && objectInfo.Size != fileEntity.SourceFileSize {
+		issueEntity.IssueType = fileAuditIssueSizeMismatch
+		issueEntity.ExpectedValue = strconv.FormatInt(fileEntity.SourceFileSize, 10)
+		issueEntity.ActualValue = strconv.FormatInt(objectInfo.Size, 10)
+	} else if fileEntity.Crc64 != "" && objectInfo.Crc64 != "" && fileEntity.Crc64 != objectInfo.Crc64 {
+		issueEntity.IssueType = fileAuditIssueChecksumMismatch
+		issueEntity.ExpectedValue = "crc64:" + fileEntity.Crc64
+		issueEntity.ActualValue = "crc64:" + objectInfo.Crc64
+	} else if fileEntity.ETag != "" && objectInfo.ETag != "" && !strings.EqualFold(fileEntity.ETag, objectInfo.ETag) {
+		issueEntity.IssueType = fileAuditIssueChecksumMismatch
+		issueEntity.ExpectedValue = "etag:" + fileEntity.ETag
+		issueEntity.ActualValue = "etag:" + objectInfo.ETag
+	} else {
+		return nil
+	}
+
+	return issueEntity
+}
+
+func truncateString(s string, maxLength int) string {
+	if runes := []rune(s); len(runes) > maxLength {
+		return string(runes[:maxLength])
+	}
+
+	return s
+}
@@ -0,0 +1,39 @@
+package object
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/storage"
+)
+
+func Test_checkFileObject(t *testing.T) {
+	backend, err := storage.NewLocalBackend(storage.Config{Provider: storage.ProviderLocal, Endpoint: t.TempDir(), AccessKeySecret: "secret"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := backend.Put("test", "a.txt", strings.NewReader("hello"), storage.PutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		entity    repository.File
+		issueType int
+	}{
+		{repository.File{Id: 1, FileKey: "a.txt", SourceFileSize: 5}, 0},
+		{repository.File{Id: 2, FileKey: "a.txt", SourceFileSize: 6}, fileAuditIssueSizeMismatch},
+		{repository.File{Id: 3, FileKey: "a.txt", SourceFileSize: 5, ETag: "not-the-etag"}, fileAuditIssueChecksumMismatch},
+		{repository.File{Id: 4, FileKey: "missing.txt", SourceFileSize: 5}, fileAuditIssueMissing},
+	} {
+		issueEntity := checkFileObject(backend, "test", c.entity)
+
+		if c.issueType == 0 && issueEntity != nil {
+			t.Errorf("unexpected issue for file %d: %+v", c.entity.Id, issueEntity)
+		} else if c.issueType != 0 && (issueEntity == nil || issueEntity.IssueType != c.issueType || issueEntity.FileId != c.entity.Id) {
+			t.Errorf("expected issue %d for file %d, got %+v", c.issueType, c.entity.Id, issueEntity)
+		}
+	}
+}
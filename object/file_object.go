@@ -0,0 +1,87 @@
+package object
+
+import (
+	"github.com/dromara/carbon/v2"
+	"github.com/easynet-cn/file-service/log"
+	"github.com/easynet-cn/file-service/repository"
+	"go.uber.org/zap"
+	"xorm.io/xorm"
+)
+
+// 去重复用对象时增加引用数；引用数为0表示对象已删除或正在删除，不能复用。
+// 去重前创建的文件没有引用记录，按现有文件数创建，唯一索引冲突时说明其他请求已创建，重新增加引用
+func attachFileObject(engine *xorm.Engine, bucketId int64, fileKey string) bool {
+	for range 2 {
+		if affected, err := repository.FileObjectRepository.IncreaseRefCount(engine, bucketId, fileKey); err != nil {
+			log.Logger.Error("repository.IncreaseFileObjectRefCount", zap.String("fileKey", fileKey), zap.Error(err))
+
+			return false
+		} else if affected > 0 {
+			return true
+		} else if entity, err := repository.FileObjectRepository.FindByBucketIdAndFileKey(engine, bucketId, fileKey); err != nil || entity.Id > 0 {
+			return false
+		} else if count, err := repository.FileRepository.CountOthersByBucketIdAndFileKey(engine, 0, bucketId, fileKey); err != nil || count == 0 {
+			return false
+		} else if err := createFileObject(engine, bucketId, fileKey, count+1); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// 复用对象后创建文件记录失败时撤销引用
+func detachFileObject(engine *xorm.Engine, bucketId int64, fileKey string) {
+	if _, err := repository.FileObjectRepository.DecreaseRefCount(engine, bucketId, fileKey); err != nil {
+		log.Logger.Error("repository.DecreaseFileObjectRefCount", zap.String("fileKey", fileKey), zap.Error(err))
+	}
+}
+
+// 文件清除后减少引用数，返回是否可以删除对象。引用数减到0后不会再被复用，
+// 同一文件key还可能被未去重的文件记录引用（如指定文件key覆盖上传），因此仍需确认没有其他文件记录；
+// 启用去重的存储空间在没有引用记录时先写入引用数为0的记录再删除对象，防止删除期间被复用
+func releaseFileObject(engine *xorm.Engine, entity repository.File, bucketEntity repository.Bucket) (bool, error) {
+	for range 2 {
+		if affected, err := repository.FileObjectRepository.DecreaseRefCount(engine, entity.BucketId, entity.FileKey); err != nil {
+			return false, err
+		} else if affected > 0 {
+			if fileObject, err := repository.FileObjectRepository.FindByBucketIdAndFileKey(engine, entity.BucketId, entity.FileKey); err != nil {
+				return false, err
+			} else if fileObject.RefCount > 0 {
+				return false, nil
+			}
+
+			break
+		} else if fileObject, err := repository.FileObjectRepository.FindByBucketIdAndFileKey(engine, entity.BucketId, entity.FileKey); err != nil {
+			return false, err
+		} else if fileObject.Id > 0 {
+			break
+		} else if count, err := repository.FileRepository.CountOthersByBucketIdAndFileKey(engine, entity.Id, entity.BucketId, entity.FileKey); err != nil {
+			return false, err
+		} else if count > 0 {
+			return false, nil
+		} else if bucketEntity.Dedup != 1 {
+			return true, nil
+		} else if err := createFileObject(engine, entity.BucketId, entity.FileKey, 0); err == nil {
+			return true, nil
+		}
+	}
+
+	if count, err := repository.FileRepository.CountOthersByBucketIdAndFileKey(engine, entity.Id, entity.BucketId, entity.FileKey); err != nil {
+		return false, err
+	} else {
+		return count == 0, nil
+	}
+}
+
+func createFileObject(engine *xorm.Engine, bucketId int64, fileKey string, refCount int64) error {
+	now := carbon.Now().ToDateTimeString()
+
+	return repository.FileObjectRepository.Create(engine, &repository.FileObject{
+		BucketId:   bucketId,
+		FileKey:    fileKey,
+		RefCount:   refCount,
+		CreateTime: now,
+		UpdateTime: now,
+	})
+}
@@ -0,0 +1,167 @@
+package object
+
+import (
+	"errors"
+
+	"github.com/dromara/carbon/v2"
+	"github.com/easynet-cn/file-service/log"
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/storage"
+	"github.com/easynet-cn/winter"
+	"go.uber.org/zap"
+	"xorm.io/xorm"
+)
+
+const (
+	fileStatusActive            = 0
+	fileStatusPending           = 1
+	fileStatusFailed            = 2
+	expirePendingFilesBatchSize = 500
+)
+
+func ConfirmFileById(id int64, param ConfirmFileParam) (*File, error) {
+	engine := GetDB()
+
+	fileEntity, err := repository.FileRepository.FindById(engine, id)
+
+	if err != nil {
+		return nil, err
+	} else if fileEntity.Id == 0 {
+		return nil, winter.NewNotFoundBusinessError("文件不存在")
+	}
+
+	if fileEntity.Status != fileStatusActive {
+		bucketEntity, err := repository.BucketRepository.FindById(engine, fileEntity.BucketId)
+
+		if err != nil {
+			return nil, err
+		} else if bucketEntity.Id == 0 {
+			return nil, winter.NewNotFoundBusinessError("存储空间不存在")
+		}
+
+		backend, err := getBackendByBucket(engine, *bucketEntity, accessWrite)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if objectInfo, err := backend.Head(bucketEntity.Name, fileEntity.FileKey); errors.Is(err, storage.ErrObjectNotFound) {
+			if fileEntity.Status == fileStatusPending && fileEntity.UploadExpireAt >= carbon.Now().Timestamp() {
+				return nil, winter.NewBadRequestBusinessError("文件尚未上传")
+			}
+
+			if affected, err := updateFileStatus(engine, fileEntity, fileStatusFailed, nil); err != nil {
+				return nil, err
+			} else if affected > 0 {
+				return nil, winter.NewBadRequestBusinessError("文件上传已过期")
+			}
+		} else if err != nil {
+			log.Logger.Error("backend.Head", zap.String("bucketName", bucketEntity.Name), zap.String("fileKey", fileEntity.FileKey), zap.Error(err))
+
+			return nil, err
+		} else if _, err := updateFileStatus(engine, fileEntity, fileStatusActive, objectInfo); err != nil {
+			return nil, err
+		}
+	}
+
+	if ms, err := SearchFiles(SearchFileParam{Ids: []int64{id}, ExpiredInSec: param.ExpiredInSec, ProcessParams: param.ProcessParams}); err != nil {
+		return nil, err
+	} else if len(ms) == 0 {
+		return nil, winter.NewNotFoundBusinessError("文件所属的存储空间或应用已删除")
+	} else {
+		return &ms[0], nil
+	}
+}
+
+// 过期未确认的文件先检查对象是否已上传，已上传的直接确认，否则标记为上传失败
+func ExpirePendingFiles() {
+	engine := GetDB()
+	uploadExpireAt := carbon.Now().Timestamp()
+	backendMap := make(map[int64]storage.Backend)
+	bucketMap := make(map[int64]*repository.Bucket)
+
+	for {
+		fileEntities, err := repository.FileRepository.FindPendingByUploadExpireAtBefore(engine, uploadExpireAt, expirePendingFilesBatchSize)
+
+		if err != nil {
+			log.Logger.Error("repository.FindPendingFiles", zap.Error(err))
+
+			return
+		}
+
+		expired := 0
+
+		for _, fileEntity := range fileEntities {
+			bucketEntity, ok := bucketMap[fileEntity.BucketId]
+
+			if !ok {
+				if bucketEntity, err = repository.BucketRepository.FindById(engine, fileEntity.BucketId); err != nil {
+					log.Logger.Error("repository.FindBucketById", zap.Int64("bucketId", fileEntity.BucketId), zap.Error(err))
+
+					continue
+				}
+
+				bucketMap[fileEntity.BucketId] = bucketEntity
+			}
+
+			var objectInfo *storage.ObjectInfo
+
+			status := fileStatusFailed
+
+			if bucketEntity.Id > 0 {
+				backend, ok := backendMap[bucketEntity.Id]
+
+				if !ok {
+					if backend, err = getBackendByBucket(engine, *bucketEntity, accessAny); err != nil {
+						log.Logger.Error("getBackendByBucket", zap.Int64("bucketId", bucketEntity.Id), zap.Error(err))
+
+						continue
+					}
+
+					backendMap[bucketEntity.Id] = backend
+				}
+
+				if objectInfo, err = backend.Head(bucketEntity.Name, fileEntity.FileKey); err == nil {
+					status = fileStatusActive
+				} else if !errors.Is(err, storage.ErrObjectNotFound) {
+					log.Logger.Error("backend.Head", zap.String("bucketName", bucketEntity.Name), zap.String("fileKey", fileEntity.FileKey), zap.Error(err))
+
+					continue
+				}
+			}
+
+			if affected, err := updateFileStatus(engine, &fileEntity, status, objectInfo); err != nil {
+				log.Logger.Error("updateFileStatus", zap.Int64("fileId", fileEntity.Id), zap.Error(err))
+			} else if affected > 0 {
+				expired++
+			}
+		}
+
+		if expired > 0 {
+			log.Logger.Info("处理过期待上传文件", zap.Int("count", expired))
+		}
+
+		if len(fileEntities) < expirePendingFilesBatchSize || expired == 0 {
+			return
+		}
+	}
+}
+
+// 只在状态仍为读取时的状态时更新，与上传回调和确认请求并发时返回的更新行数为0，表示文件已被处理
+func updateFileStatus(engine *xorm.Engine, fileEntity *repository.File, status int, objectInfo *storage.ObjectInfo) (int64, error) {
+	cols := []string{"status", "update_time"}
+	previousStatus := fileEntity.Status
+
+	fileEntity.Status = status
+	fileEntity.UpdateTime = carbon.Now().ToDateTimeString()
+
+	if objectInfo != nil {
+		fileEntity.SourceFileSize = objectInfo.Size
+		fileEntity.ContentType = objectInfo.ContentType
+		fileEntity.ETag = objectInfo.ETag
+
+		cols = append(cols, "source_file_size", "content_type", "etag")
+	}
+
+	return repository.FileRepository.UpdateByStatus(engine, cols, fileEntity, previousStatus)
+}
@@ -0,0 +1,6 @@
+package object
+
+type ConfirmFileParam struct {
+	ExpiredInSec  int64          `json:"expiredInSec"`  //过期秒数
+	ProcessParams []ProcessParam `json:"processParams"` //处理参数
+}
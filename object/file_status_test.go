@@ -0,0 +1,31 @@
+package object
+
+import (
+	"testing"
+
+	"github.com/easynet-cn/file-service/repository"
+)
+
+func Test_updateFileStatus(t *testing.T) {
+	engine, _ := setupTestDB(t)
+	now := "2026-01-01 00:00:00"
+	fileEntity := &repository.File{BucketId: 1, FileKey: "a.png", Status: fileStatusPending, CreateTime: now, UpdateTime: now}
+
+	if _, err := engine.Insert(fileEntity); err != nil {
+		t.Fatal(err)
+	}
+
+	staleEntity := *fileEntity
+
+	if affected, err := updateFileStatus(engine, fileEntity, fileStatusActive, nil); err != nil || affected != 1 {
+		t.Fatalf("expected pending file activated, got %d %v", affected, err)
+	}
+
+	if affected, err := updateFileStatus(engine, &staleEntity, fileStatusFailed, nil); err != nil || affected != 0 {
+		t.Errorf("expected stale update skipped, got %d %v", affected, err)
+	}
+
+	if entity, err := repository.FileRepository.FindById(engine, fileEntity.Id); err != nil || entity.Status != fileStatusActive {
+		t.Errorf("expected file still active, got %+v %v", entity, err)
+	}
+}
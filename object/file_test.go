@@ -1,10 +1,16 @@
 package object
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/easynet-cn/file-service/repository"
 )
 
 func Test_getUrl(t *testing.T) {
@@ -15,3 +21,129 @@ func Test_getUrl(t *testing.T) {
 
 	fmt.Println(oss.GetRawParams(options))
 }
+
+func TestGetUploadToken(t *testing.T) {
+	engine, appEntity := setupTestDB(t)
+
+	createTestBucket(t, engine, repository.Bucket{AppId: appEntity.Id, BucketType: 2, Name: "private"})
+	createTestBucket(t, engine, repository.Bucket{AppId: appEntity.Id, BucketType: 2, Name: "limited", UploadConfig: `{"maxSize":10}`})
+
+	for name, c := range map[string]struct {
+		param  OssUploadFile
+		method string
+		failed bool
+	}{
+		"post":        {OssUploadFile{Bucket: "private", SourceFile: "a.png", SourceFileSize: 5}, http.MethodPost, false},
+		"put":         {OssUploadFile{Bucket: "private", SourceFile: "a.png", UploadMode: uploadModePut}, http.MethodPut, false},
+		"size-limit":  {OssUploadFile{Bucket: "limited", SourceFile: "a.png", SourceFileSize: 100}, "", true},
+		"put-limited": {OssUploadFile{Bucket: "limited", SourceFile: "a.png", UploadMode: uploadModePut}, "", true},
+		"callback":    {OssUploadFile{Bucket: "private", SourceFile: "a.png", Callback: 1}, "", true},
+	} {
+		uploadToken, err := GetUploadToken(c.param)
+
+		if c.failed {
+			if err == nil {
+				t.Errorf("%s: expected error, got %+v", name, uploadToken)
+			}
+
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unexpected error %v", name, err)
+
+			continue
+		}
+
+		fileEntity := &repository.File{}
+
+		if _, err := engine.ID(uploadToken.FileId).Get(fileEntity); err != nil {
+			t.Fatal(err)
+		}
+
+		if uploadToken.Method != c.method || uploadToken.UploadUrl == "" || !strings.HasSuffix(uploadToken.Key, ".png") {
+			t.Errorf("%s: unexpected token %+v", name, uploadToken)
+		} else if fileEntity.FileKey != uploadToken.Key || fileEntity.Status != fileStatusPending {
+			t.Errorf("%s: unexpected file %+v", name, fileEntity)
+		} else if c.method == http.MethodPost && (uploadToken.Policy == "" || uploadToken.Signature == "") {
+			t.Errorf("%s: unexpected post fields %+v", name, uploadToken)
+		}
+	}
+
+	if count, err := engine.Count(&repository.File{}); err != nil || count != 2 {
+		t.Errorf("expected rejected tokens not to create files, got %d %v", count, err)
+	}
+}
+
+func TestUploadFile(t *testing.T) {
+	engine, appEntity := setupTestDB(t)
+
+	createTestBucket(t, engine, repository.Bucket{AppId: appEntity.Id, BucketType: 2, Name: "private"})
+	createTestBucket(t, engine, repository.Bucket{AppId: appEntity.Id, BucketType: 2, Name: "dedup", Dedup: 1})
+
+	readOnly := createTestBucket(t, engine, repository.Bucket{AppId: appEntity.Id, BucketType: 2, Name: "readonly"})
+
+	if _, err := engine.ID(readOnly.Id).Cols("status").Update(&repository.Bucket{Status: StatusReadOnly}); err != nil {
+		t.Fatal(err)
+	}
+
+	backend, err := getBackendByApp(appEntity)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := sha256.Sum256([]byte("hello"))
+	fileKeys := make(map[string]string)
+
+	for _, c := range []struct {
+		name   string
+		param  OssUploadFile
+		failed bool
+	}{
+		{"private", OssUploadFile{Bucket: "private", SourceFile: "a.txt"}, false},
+		{"checksum", OssUploadFile{Bucket: "private", SourceFile: "b.txt", Sha256: hex.EncodeToString(sum[:])}, false},
+		{"bad-md5", OssUploadFile{Bucket: "private", SourceFile: "c.txt", ContentMD5: "eV8yArF8trw9S3cdjGyerw=="}, true},
+		{"dedup-first", OssUploadFile{Bucket: "dedup", SourceFile: "d.txt"}, false},
+		{"dedup-second", OssUploadFile{Bucket: "dedup", SourceFile: "e.txt"}, false},
+		{"readonly", OssUploadFile{Bucket: "readonly", SourceFile: "f.txt"}, true},
+	} {
+		m, err := UploadFile(c.param, strings.NewReader("hello"))
+
+		if c.failed {
+			if err == nil {
+				t.Errorf("%s: expected error, got %+v", c.name, m)
+			}
+
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+
+			continue
+		}
+
+		fileKeys[c.name] = m.FileKey
+
+		if m.Id == 0 || m.SourceFileSize != 5 || m.Sha256 != hex.EncodeToString(sum[:]) || m.ContentType != "text/plain; charset=utf-8" {
+			t.Errorf("%s: unexpected file %+v", c.name, m)
+		} else if reader, err := backend.Get(c.param.Bucket, m.FileKey); err != nil {
+			t.Errorf("%s: expected object stored, got %v", c.name, err)
+		} else {
+			bytes, _ := io.ReadAll(reader)
+
+			reader.Close()
+
+			if string(bytes) != "hello" {
+				t.Errorf("%s: unexpected content %s", c.name, bytes)
+			}
+		}
+	}
+
+	if fileKeys["dedup-first"] == "" || fileKeys["dedup-first"] != fileKeys["dedup-second"] {
+		t.Errorf("expected dedup to reuse object, got %v", fileKeys)
+	} else if result, err := backend.List("dedup", "", "", 10); err != nil || len(result.Objects) != 1 {
+		t.Errorf("expected single dedup object, got %+v %v", result, err)
+	}
+
+	if count, err := engine.Count(&repository.File{}); err != nil || count != 4 {
+		t.Errorf("expected failed uploads not to create files, got %d %v", count, err)
+	}
+}
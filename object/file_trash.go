@@ -0,0 +1,134 @@
+package object
+
+import (
+	"github.com/dromara/carbon/v2"
+	"github.com/easynet-cn/file-service/log"
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/winter"
+	"go.uber.org/zap"
+)
+
+const (
+	purgeTrashFilesBatchSize = 500
+)
+
+func SearchPageTrashFiles(searchParam SearchFilePageParam) (winter.PageResult, error) {
+	return searchPageFiles(searchParam, 1)
+}
+
+func RestoreFileById(id int64) (*File, error) {
+	engine := GetDB()
+
+	if fileEntity, err := repository.FileRepository.FindDeletedById(engine, id); err != nil {
+		return nil, err
+	} else if fileEntity.Id == 0 {
+		return nil, winter.NewNotFoundBusinessError("回收站中不存在该文件")
+	} else if err := checkBucketAccessById(engine, fileEntity.BucketId, accessWrite); err != nil {
+		return nil, err
+	} else if _, err := repository.FileRepository.RestoreById(engine, id); err != nil {
+		return nil, err
+	} else if ms, err := SearchFiles(SearchFileParam{Ids: []int64{id}}); err != nil {
+		return nil, err
+	} else if len(ms) == 0 {
+		return nil, winter.NewNotFoundBusinessError("文件所属的存储空间或应用已删除")
+	} else {
+		return &ms[0], nil
+	}
+}
+
+func PurgeTrashFiles() {
+	engine := GetDB()
+
+	// 按回收站中的文件查找存储空间，包含已删除的存储空间和应用，否则其回收站中的文件永远不会被清除
+	bucketIds, err := repository.FileRepository.FindDeletedBucketIds(engine)
+
+	if err != nil {
+		log.Logger.Error("repository.FindDeletedBucketIds", zap.Error(err))
+
+		return
+	} else if len(bucketIds) == 0 {
+		return
+	}
+
+	bucketEntities, err := repository.BucketRepository.FindByIdIn(engine, bucketIds)
+
+	if err != nil {
+		log.Logger.Error("repository.FindBucketsByIdIn", zap.Error(err))
+
+		return
+	}
+
+	appIds := make([]int64, 0, len(bucketEntities))
+
+	for _, bucketEntity := range bucketEntities {
+		appIds = append(appIds, bucketEntity.AppId)
+	}
+
+	appEntities, err := repository.AppRepository.FindByIdIn(engine, appIds)
+
+	if err != nil {
+		log.Logger.Error("repository.FindAppsByIdIn", zap.Error(err))
+
+		return
+	}
+
+	appEntityMap := make(map[int64]repository.App, len(appEntities))
+
+	for _, appEntity := range appEntities {
+		appEntityMap[appEntity.Id] = appEntity
+	}
+
+	for _, bucketEntity := range bucketEntities {
+		appEntity, ok := appEntityMap[bucketEntity.AppId]
+		deleted := bucketEntity.DelStatus != 0 || appEntity.DelStatus != 0
+
+		if !ok {
+			log.Logger.Error("应用不存在", zap.Int64("appId", bucketEntity.AppId))
+
+			continue
+		} else if !deleted {
+			if err := checkAccess(appEntity, bucketEntity, accessWrite); err != nil {
+				log.Logger.Info("跳过清除回收站", zap.String("bucketName", bucketEntity.Name), zap.Error(err))
+
+				continue
+			}
+		}
+
+		// 已关闭回收站以及已删除的存储空间中的文件无法恢复，立即清除
+		retentionDays := bucketEntity.TrashRetentionDays
+
+		if deleted {
+			retentionDays = 0
+		}
+
+		updateTime := carbon.Now().SubDays(retentionDays).ToDateTimeString()
+
+		for {
+			fileEntities, err := repository.FileRepository.FindDeletedByBucketIdAndUpdateTimeBefore(engine, bucketEntity.Id, updateTime, purgeTrashFilesBatchSize)
+
+			if err != nil {
+				log.Logger.Error("repository.FindDeletedFiles", zap.Int64("bucketId", bucketEntity.Id), zap.Error(err))
+
+				break
+			}
+
+			purged := 0
+
+			for _, fileEntity := range fileEntities {
+				if err := purgeFile(engine, fileEntity, bucketEntity, appEntity); err != nil {
+					log.Logger.Error("purgeFile", zap.Int64("fileId", fileEntity.Id), zap.Error(err))
+				} else {
+					purged++
+				}
+			}
+
+			if purged > 0 {
+				log.Logger.Info("清除回收站文件", zap.String("bucketName", bucketEntity.Name), zap.Int("count", purged))
+			}
+
+			if len(fileEntities) < purgeTrashFilesBatchSize || purged == 0 {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package object
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/storage"
+)
+
+func TestPurgeTrashFiles_deletedBucket(t *testing.T) {
+	engine, appEntity := setupTestDB(t)
+
+	bucketEntity := createTestBucket(t, engine, repository.Bucket{AppId: appEntity.Id, BucketType: 2, Name: "trash", TrashRetentionDays: 30})
+
+	backend, err := getBackendByApp(appEntity)
+
+	if err != nil {
+		t.Fatal(err)
+	} else if err := backend.Put("trash", "a.txt", strings.NewReader("a"), storage.PutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := "2026-01-01 00:00:00"
+	fileEntity := &repository.File{BucketId: bucketEntity.Id, FileKey: "a.txt", Status: fileStatusActive, DelStatus: 1, CreateTime: now, UpdateTime: now}
+
+	if _, err := engine.Insert(fileEntity); err != nil {
+		t.Fatal(err)
+	} else if _, err := repository.BucketRepository.DeleteById(engine, bucketEntity.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	PurgeTrashFiles()
+
+	if entity, err := repository.FileRepository.FindDeletedById(engine, fileEntity.Id); err != nil || entity.Id != 0 {
+		t.Errorf("expected trash in deleted bucket purged, got %+v %v", entity, err)
+	}
+
+	if _, err := backend.Head("trash", "a.txt"); err == nil {
+		t.Error("expected object deleted")
+	}
+}
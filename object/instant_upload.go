@@ -0,0 +1,236 @@
+package object
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dromara/carbon/v2"
+	"github.com/easynet-cn/file-service/log"
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/storage"
+	"github.com/easynet-cn/winter"
+	"go.uber.org/zap"
+)
+
+const (
+	instantUploadChallengeSize      = 64 * 1024
+	instantUploadChallengeExpiredIn = 300
+)
+
+var (
+	sha256HexRegexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+type InstantUploadChallenge struct {
+	Offset     int64  `json:"offset"`     //需要计算摘要的字节偏移
+	Length     int64  `json:"length"`     //需要计算摘要的字节数
+	Token      string `json:"token"`      //挑战令牌，证明时作为challengeToken原样传回
+	ExpireTime string `json:"expireTime"` //过期时间
+}
+
+type InstantUploadResult struct {
+	Hit         bool                    `json:"hit"`         //是否秒传成功
+	File        *File                   `json:"file"`        //秒传成功时的文件
+	UploadToken *OssUploadToken         `json:"uploadToken"` //未命中时的上传凭证
+	Challenge   *InstantUploadChallenge `json:"challenge"`   //存在相同内容时的持有证明挑战
+}
+
+// 秒传：存储空间启用去重且已存在内容相同的有效文件时直接创建文件数据，否则返回普通上传凭证。
+// 摘要和大小可能从其他渠道获得，命中前要求客户端计算服务端随机选择的字节范围的摘要，证明持有文件内容，
+// 第一次请求返回挑战，携带挑战令牌和摘要再次请求时校验
+func InstantUpload(param OssUploadFile) (*InstantUploadResult, error) {
+	sha256 := strings.ToLower(param.Sha256)
+
+	if !sha256HexRegexp.MatchString(sha256) {
+		return nil, winter.NewBadRequestBusinessError("SHA-256摘要格式不正确")
+	} else if param.SourceFileSize <= 0 {
+		return nil, winter.NewBadRequestBusinessError("源文件大小必须大于0")
+	}
+
+	engine := GetDB()
+
+	if ossBucket, err := repository.BucketRepository.FindByName(engine, param.Bucket); err != nil || ossBucket.Id == 0 {
+		return nil, err
+	} else if appEntity, err := repository.AppRepository.FindById(engine, ossBucket.AppId); err != nil || appEntity.Id == 0 {
+		return nil, err
+	} else if err := checkAccess(*appEntity, *ossBucket, accessWrite); err != nil {
+		return nil, err
+	} else if backend, err := getBackendByApp(*appEntity); err != nil {
+		return nil, err
+	} else if dedupEntity := findDedupCandidate(engine, *ossBucket, backend, param, sha256, param.SourceFileSize); dedupEntity == nil || getOssCallbackSecret() == "" {
+		return instantUploadMiss(param)
+	} else if param.ChallengeToken == "" {
+		return &InstantUploadResult{Challenge: newInstantUploadChallenge(*ossBucket, sha256, param.SourceFileSize)}, nil
+	} else if err := verifyInstantUploadChallenge(*ossBucket, backend, dedupEntity.FileKey, sha256, param); err != nil {
+		return nil, err
+	} else if !attachFileObject(engine, ossBucket.Id, dedupEntity.FileKey) {
+		return instantUploadMiss(param)
+	} else {
+		contentType := param.ContentType
+
+		if contentType == "" {
+			contentType = dedupEntity.ContentType
+		}
+
+		uploadConfig, err := mergeUploadConfig(parseUploadConfig(ossBucket.UploadConfig), param.UploadConfig)
+
+		if err == nil {
+			err = checkUploadContentType(uploadConfig, contentType)
+		}
+
+		if err == nil {
+			err = checkUploadFileSize(uploadConfig, dedupEntity.SourceFileSize)
+		}
+
+		if err != nil {
+			detachFileObject(engine, ossBucket.Id, dedupEntity.FileKey)
+
+			return nil, err
+		}
+
+		now := carbon.Now().ToDateTimeString()
+
+		fileEntity := &repository.File{
+			BucketId:       ossBucket.Id,
+			FileKey:        dedupEntity.FileKey,
+			SourceFile:     param.SourceFile,
+			SourceFileType: param.SourceFileType,
+			SourceFileSize: dedupEntity.SourceFileSize,
+			SourceFileAttr: param.SourceFileAttr,
+			ContentType:    contentType,
+			ETag:           dedupEntity.ETag,
+			Sha256:         dedupEntity.Sha256,
+			Md5:            dedupEntity.Md5,
+			Crc64:          dedupEntity.Crc64,
+			ImageWidth:     dedupEntity.ImageWidth,
+			ImageHeight:    dedupEntity.ImageHeight,
+			ImageFormat:    dedupEntity.ImageFormat,
+			CreateTime:     now,
+			UpdateTime:     now,
+		}
+
+		if err := repository.FileRepository.Create(engine, fileEntity); err != nil || fileEntity.Id == 0 {
+			log.Logger.Error("repository.CreateFile", zap.Any("fileEntity", fileEntity), zap.Error(err))
+
+			detachFileObject(engine, ossBucket.Id, dedupEntity.FileKey)
+
+			return nil, err
+		}
+
+		return &InstantUploadResult{
+			Hit: true,
+			File: &File{
+				Id:             fileEntity.Id,
+				BucketId:       ossBucket.Id,
+				BucketName:     ossBucket.Name,
+				Domain:         ossBucket.Domain,
+				FileKey:        fileEntity.FileKey,
+				SourceFile:     fileEntity.SourceFile,
+				SourceFileSize: fileEntity.SourceFileSize,
+				SourceFileType: fileEntity.SourceFileType,
+				SourceFileAttr: fileEntity.SourceFileAttr,
+				ContentType:    fileEntity.ContentType,
+				ETag:           fileEntity.ETag,
+				Sha256:         fileEntity.Sha256,
+				Md5:            fileEntity.Md5,
+				Crc64:          fileEntity.Crc64,
+				ImageWidth:     fileEntity.ImageWidth,
+				ImageHeight:    fileEntity.ImageHeight,
+				ImageFormat:    fileEntity.ImageFormat,
+				Url:            getUrl(backend, *ossBucket, fileEntity.FileKey, param.ExpiredInSec, param.ProcessParams),
+				CreateTime:     now,
+				UpdateTime:     now,
+			},
+		}, nil
+	}
+}
+
+func instantUploadMiss(param OssUploadFile) (*InstantUploadResult, error) {
+	if uploadToken, err := GetUploadToken(param); err != nil {
+		return nil, err
+	} else {
+		return &InstantUploadResult{UploadToken: uploadToken}, nil
+	}
+}
+
+// 挑战令牌格式为“偏移.字节数.过期时间戳.签名”，使用服务端回调秘钥签名，不需要在服务端保存挑战。
+// 应用访问秘钥存储的是密文且轮换时会变化，不适合作为签名秘钥
+func newInstantUploadChallenge(bucketEntity repository.Bucket, contentSha256 string, size int64) *InstantUploadChallenge {
+	length := min(size, instantUploadChallengeSize)
+	offset := int64(0)
+
+	if n, err := rand.Int(rand.Reader, big.NewInt(size-length+1)); err == nil {
+		offset = n.Int64()
+	}
+
+	expireAt := time.Now().Unix() + instantUploadChallengeExpiredIn
+
+	return &InstantUploadChallenge{
+		Offset:     offset,
+		Length:     length,
+		Token:      fmt.Sprintf("%d.%d.%d.%s", offset, length, expireAt, signInstantUploadChallenge(bucketEntity, contentSha256, size, offset, length, expireAt)),
+		ExpireTime: carbon.CreateFromTimestamp(expireAt).ToDateTimeString(),
+	}
+}
+
+func signInstantUploadChallenge(bucketEntity repository.Bucket, contentSha256 string, size int64, offset int64, length int64, expireAt int64) string {
+	mac := hmac.New(sha256.New, []byte(getOssCallbackSecret()))
+	mac.Write([]byte(fmt.Sprintf("%d\n%s\n%d\n%d\n%d\n%d", bucketEntity.Id, contentSha256, size, offset, length, expireAt)))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func verifyInstantUploadChallenge(bucketEntity repository.Bucket, backend storage.Backend, fileKey string, contentSha256 string, param OssUploadFile) error {
+	parts := strings.Split(param.ChallengeToken, ".")
+
+	if len(parts) != 4 {
+		return winter.NewBadRequestBusinessError("挑战令牌格式不正确")
+	}
+
+	values := make([]int64, 3)
+
+	for i := range values {
+		if v, err := strconv.ParseInt(parts[i], 10, 64); err != nil {
+			return winter.NewBadRequestBusinessError("挑战令牌格式不正确")
+		} else {
+			values[i] = v
+		}
+	}
+
+	offset, length, expireAt := values[0], values[1], values[2]
+
+	if !hmac.Equal([]byte(signInstantUploadChallenge(bucketEntity, contentSha256, param.SourceFileSize, offset, length, expireAt)), []byte(parts[3])) {
+		return winter.NewBadRequestBusinessError("挑战令牌无效")
+	} else if time.Now().Unix() > expireAt {
+		return winter.NewBadRequestBusinessError("挑战令牌已过期")
+	}
+
+	reader, err := backend.GetRange(bucketEntity.Name, fileKey, offset, length)
+
+	if err != nil {
+		return err
+	}
+
+	defer reader.Close()
+
+	hash := sha256.New()
+
+	if n, err := io.Copy(hash, reader); err != nil {
+		return err
+	} else if n != length {
+		return winter.NewBadRequestBusinessError("挑战字节范围读取不完整")
+	} else if !hmac.Equal([]byte(hex.EncodeToString(hash.Sum(nil))), []byte(strings.ToLower(param.ChallengeHash))) {
+		return winter.NewBadRequestBusinessError("挑战摘要不正确")
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package object
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/storage"
+	"github.com/easynet-cn/winter"
+)
+
+var (
+	// 本地存储与接口同源，只有不会被浏览器当作页面执行的类型允许内联展示
+	localInlineContentTypes = map[string]bool{
+		"image/png":       true,
+		"image/jpeg":      true,
+		"image/gif":       true,
+		"image/webp":      true,
+		"image/bmp":       true,
+		"image/x-icon":    true,
+		"audio/mpeg":      true,
+		"audio/ogg":       true,
+		"audio/wav":       true,
+		"video/mp4":       true,
+		"video/webm":      true,
+		"video/ogg":       true,
+		"text/plain":      true,
+		"application/pdf": true,
+	}
+)
+
+func GetLocalFile(bucketName string, fileKey string, expires string, signature string) (io.ReadCloser, *storage.ObjectInfo, error) {
+	bucketEntity, verifier, backend, err := getLocalBackend(bucketName, accessRead)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if bucketEntity.BucketType != 1 {
+		if expiresInt, err := strconv.ParseInt(expires, 10, 64); err != nil {
+			return nil, nil, winter.NewForbiddenBusinessError(storage.ErrInvalidSignature.Error())
+		} else if err := verifier.VerifyURL(http.MethodGet, bucketName, fileKey, nil, expiresInt, signature); err != nil {
+			return nil, nil, winter.NewForbiddenBusinessError(err.Error())
+		}
+	}
+
+	if objectInfo, err := backend.Head(bucketName, fileKey); err != nil {
+		return nil, nil, winter.NewNotFoundBusinessError("文件不存在")
+	} else if reader, err := backend.Get(bucketName, fileKey); err != nil {
+		return nil, nil, err
+	} else {
+		return reader, objectInfo, nil
+	}
+}
+
+// GetLocalFileHeaders 返回本地文件下载的内容类型和响应头，上传者指定的内容类型不在白名单内时强制下载，防止同源存储型XSS
+func GetLocalFileHeaders(fileKey string, contentType string) (string, map[string]string) {
+	headers := map[string]string{"X-Content-Type-Options": "nosniff"}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+
+	if err != nil || mediaType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	if !localInlineContentTypes[mediaType] {
+		headers["Content-Disposition"] = mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(fileKey)})
+	}
+
+	return contentType, headers
+}
+
+func PutLocalFile(bucketName string, fileKey string, fields map[string]string, size int64, policy string, signature string, reader io.Reader) error {
+	if _, verifier, backend, err := getLocalBackend(bucketName, accessWrite); err != nil {
+		return err
+	} else if err := verifier.VerifyPostPolicy(bucketName, fileKey, fields, size, policy, signature); err != nil {
+		return winter.NewForbiddenBusinessError(err.Error())
+	} else {
+		return backend.Put(bucketName, fileKey, reader, storage.PutOptions{ContentType: fields["Content-Type"]})
+	}
+}
+
+func PutLocalFileByURL(bucketName string, fileKey string, query url.Values, header http.Header, reader io.Reader) error {
+	params := url.Values{}
+
+	if signedHeaders := query.Get(storage.LocalSignedHeadersParam); signedHeaders != "" {
+		params.Set(storage.LocalSignedHeadersParam, signedHeaders)
+
+		for _, k := range strings.Split(signedHeaders, ";") {
+			params.Set(k, header.Get(k))
+		}
+	}
+
+	if _, verifier, backend, err := getLocalBackend(bucketName, accessWrite); err != nil {
+		return err
+	} else if expiresInt, err := strconv.ParseInt(query.Get("Expires"), 10, 64); err != nil {
+		return winter.NewForbiddenBusinessError(storage.ErrInvalidSignature.Error())
+	} else if err := verifier.VerifyURL(http.MethodPut, bucketName, fileKey, params, expiresInt, query.Get("Signature")); err != nil {
+		return winter.NewForbiddenBusinessError(err.Error())
+	} else if err := backend.Put(bucketName, fileKey, reader, storage.PutOptions{ContentType: header.Get("Content-Type"), ContentMD5: header.Get("Content-MD5")}); errors.Is(err, storage.ErrBadDigest) {
+		return winter.NewBadRequestBusinessError(err.Error())
+	} else {
+		return err
+	}
+}
+
+func PutLocalFilePart(bucketName string, fileKey string, uploadId string, partNumber string, expires string, signature string, reader io.Reader, size int64) (*storage.Part, error) {
+	params := url.Values{}
+
+	params.Set("uploadId", uploadId)
+	params.Set("partNumber", partNumber)
+
+	if _, verifier, backend, err := getLocalBackend(bucketName, accessWrite); err != nil {
+		return nil, err
+	} else if expiresInt, err := strconv.ParseInt(expires, 10, 64); err != nil {
+		return nil, winter.NewForbiddenBusinessError(storage.ErrInvalidSignature.Error())
+	} else if err := verifier.VerifyURL(http.MethodPut, bucketName, fileKey, params, expiresInt, signature); err != nil {
+		return nil, winter.NewForbiddenBusinessError(err.Error())
+	} else if partNumberInt, err := strconv.Atoi(partNumber); err != nil {
+		return nil, winter.NewBadRequestBusinessError("分片号不合法")
+	} else {
+		return backend.UploadPart(bucketName, fileKey, uploadId, partNumberInt, reader, size)
+	}
+}
+
+func getLocalBackend(bucketName string, mode accessMode) (*repository.Bucket, storage.SignatureVerifier, storage.Backend, error) {
+	engine := GetDB()
+
+	if bucketEntity, err := repository.BucketRepository.FindByName(engine, bucketName); err != nil {
+		return nil, nil, nil, err
+	} else if bucketEntity.Id == 0 {
+		return nil, nil, nil, winter.NewNotFoundBusinessError("存储空间不存在")
+	} else if appEntity, err := repository.AppRepository.FindById(engine, bucketEntity.AppId); err != nil {
+		return nil, nil, nil, err
+	} else if appEntity.Id == 0 || appEntity.Provider != storage.ProviderLocal {
+		return nil, nil, nil, winter.NewNotFoundBusinessError("存储空间不存在")
+	} else if err := checkAccess(*appEntity, *bucketEntity, mode); err != nil {
+		return nil, nil, nil, err
+	} else if backend, err := getBackendByApp(*appEntity); err != nil {
+		return nil, nil, nil, err
+	} else if verifier, ok := backend.(storage.SignatureVerifier); !ok {
+		return nil, nil, nil, winter.NewNotFoundBusinessError("存储空间不存在")
+	} else {
+		return bucketEntity, verifier, backend, nil
+	}
+}
@@ -0,0 +1,25 @@
+package object
+
+import (
+	"testing"
+)
+
+func TestGetLocalFileHeaders(t *testing.T) {
+	for contentType, c := range map[string]struct {
+		contentType string
+		disposition string
+	}{
+		"":                          {"application/octet-stream", `attachment; filename=a.html`},
+		"image/png":                 {"image/png", ""},
+		"text/plain; charset=utf-8": {"text/plain; charset=utf-8", ""},
+		"text/html":                 {"text/html", `attachment; filename=a.html`},
+		"image/svg+xml":             {"image/svg+xml", `attachment; filename=a.html`},
+		"TEXT/HTML; charset=utf-8":  {"TEXT/HTML; charset=utf-8", `attachment; filename=a.html`},
+	} {
+		actual, headers := GetLocalFileHeaders("dir/a.html", contentType)
+
+		if actual != c.contentType || headers["Content-Disposition"] != c.disposition || headers["X-Content-Type-Options"] != "nosniff" {
+			t.Errorf("%q: unexpected %q %v", contentType, actual, headers)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package object
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dromara/carbon/v2"
+	"github.com/easynet-cn/file-service/log"
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/storage"
+	"github.com/easynet-cn/winter"
+	"go.uber.org/zap"
+)
+
+const (
+	ossCallbackBodyType      = "application/x-www-form-urlencoded"
+	ossCallbackPubKeyMaxSize = 64 * 1024
+)
+
+var (
+	ossCallbackPubKeyUrlPrefixes = []string{"https://gosspublic.alicdn.com/"}
+	ossCallbackPubKeyCache       = &sync.Map{}
+	ossCallbackHttpClient        = &http.Client{Timeout: 10 * time.Second}
+)
+
+type OssCallbackRequest struct {
+	PubKeyUrl     string //Base64编码的公钥地址（x-oss-pub-key-url）
+	Authorization string //Base64编码的签名
+	Path          string //请求路径
+	RawQuery      string //查询字符串
+	Body          []byte //请求体
+}
+
+func HandleOssCallback(request OssCallbackRequest) (*File, error) {
+	if err := verifyOssCallbackSignature(request); err != nil {
+		log.Logger.Warn("OSS上传回调签名校验失败", zap.String("path", request.Path), zap.Error(err))
+
+		return nil, winter.NewForbiddenBusinessError("上传回调签名校验失败")
+	}
+
+	values, err := url.ParseQuery(string(request.Body))
+
+	if err != nil {
+		return nil, winter.NewBadRequestBusinessError("上传回调请求体格式错误")
+	}
+
+	fileId, _ := strconv.ParseInt(values.Get("fileId"), 10, 64)
+
+	if !verifyOssCallbackToken(fileId, values.Get("object"), values.Get("token")) {
+		log.Logger.Warn("OSS上传回调令牌校验失败", zap.Int64("fileId", fileId), zap.String("object", values.Get("object")))
+
+		return nil, winter.NewForbiddenBusinessError("上传回调令牌校验失败")
+	}
+
+	engine := GetDB()
+	fileEntity, err := repository.FileRepository.FindById(engine, fileId)
+
+	if err != nil {
+		return nil, err
+	} else if fileEntity.Id == 0 {
+		return nil, winter.NewNotFoundBusinessError("文件不存在")
+	} else if fileEntity.Status != fileStatusPending {
+		return nil, winter.NewBadRequestBusinessError("文件不是待上传状态")
+	}
+
+	if bucketEntity, err := repository.BucketRepository.FindById(engine, fileEntity.BucketId); err != nil {
+		return nil, err
+	} else if bucketEntity.Name != values.Get("bucket") || fileEntity.FileKey != values.Get("object") {
+		return nil, winter.NewBadRequestBusinessError("上传回调与文件记录不匹配")
+	}
+
+	size, _ := strconv.ParseInt(values.Get("size"), 10, 64)
+
+	fileEntity.Status = fileStatusActive
+	fileEntity.SourceFileSize = size
+	fileEntity.ContentType = values.Get("mimeType")
+	fileEntity.ETag = strings.Trim(values.Get("etag"), `"`)
+	fileEntity.ImageWidth, _ = strconv.Atoi(values.Get("imageInfo.width"))
+	fileEntity.ImageHeight, _ = strconv.Atoi(values.Get("imageInfo.height"))
+	fileEntity.ImageFormat = values.Get("imageInfo.format")
+	fileEntity.UpdateTime = carbon.Now().ToDateTimeString()
+
+	if affected, err := repository.FileRepository.UpdateByStatus(engine, []string{"status", "source_file_size", "content_type", "etag", "image_width", "image_height", "image_format", "update_time"}, fileEntity, fileStatusPending); err != nil {
+		return nil, err
+	} else if affected == 0 {
+		return nil, winter.NewBadRequestBusinessError("文件不是待上传状态")
+	}
+
+	if ms, err := SearchFiles(SearchFileParam{Ids: []int64{fileEntity.Id}}); err != nil {
+		return nil, err
+	} else if len(ms) == 0 {
+		return nil, winter.NewNotFoundBusinessError("文件所属的存储空间或应用已删除")
+	} else {
+		return &ms[0], nil
+	}
+}
+
+// OSS回调变量由OSS在上传完成后填充；OSS的签名只能证明请求来自OSS，任何OSS账号都能让OSS向回调地址发送任意回调体，
+// 因此回调体中携带服务端秘钥对fileId和文件key计算的令牌，回调时校验令牌
+func newOssCallback(fileId int64, fileKey string) *storage.Callback {
+	return &storage.Callback{
+		Url:      getOssCallbackUrl(),
+		Body:     fmt.Sprintf("fileId=%d&token=%s&bucket=${bucket}&object=${object}&etag=${etag}&size=${size}&mimeType=${mimeType}&imageInfo.height=${imageInfo.height}&imageInfo.width=${imageInfo.width}&imageInfo.format=${imageInfo.format}", fileId, newOssCallbackToken(fileId, fileKey)),
+		BodyType: ossCallbackBodyType,
+	}
+}
+
+func newOssCallbackToken(fileId int64, fileKey string) string {
+	mac := hmac.New(sha256.New, []byte(getOssCallbackSecret()))
+	mac.Write([]byte(strconv.FormatInt(fileId, 10) + "\n" + fileKey))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func verifyOssCallbackToken(fileId int64, fileKey string, token string) bool {
+	if getOssCallbackSecret() == "" {
+		return false
+	}
+
+	return hmac.Equal([]byte(newOssCallbackToken(fileId, fileKey)), []byte(token))
+}
+
+// 签名内容为url_decode(path) + query_string + "\n" + body，使用OSS公钥以RSA-MD5校验
+func verifyOssCallbackSignature(request OssCallbackRequest) error {
+	pubKeyUrl, err := base64.StdEncoding.DecodeString(request.PubKeyUrl)
+
+	if err != nil {
+		return fmt.Errorf("公钥地址不是合法的Base64：%w", err)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(request.Authorization)
+
+	if err != nil {
+		return fmt.Errorf("签名不是合法的Base64：%w", err)
+	}
+
+	publicKey, err := getOssCallbackPublicKey(string(pubKeyUrl))
+
+	if err != nil {
+		return err
+	}
+
+	sb := new(strings.Builder)
+
+	sb.WriteString(request.Path)
+
+	if request.RawQuery != "" {
+		sb.WriteString("?")
+		sb.WriteString(request.RawQuery)
+	}
+
+	sb.WriteString("\n")
+	sb.Write(request.Body)
+
+	digest := md5.Sum([]byte(sb.String()))
+
+	return rsa.VerifyPKCS1v15(publicKey, crypto.MD5, digest[:], signature)
+}
+
+// OSS回调的公钥地址使用http，改为https获取，防止公钥在传输中被替换
+func getOssCallbackPublicKey(pubKeyUrl string) (*rsa.PublicKey, error) {
+	if strings.HasPrefix(pubKeyUrl, "http://gosspublic.alicdn.com/") {
+		pubKeyUrl = "https://" + strings.TrimPrefix(pubKeyUrl, "http://")
+	}
+
+	trusted := false
+
+	for _, prefix := range ossCallbackPubKeyUrlPrefixes {
+		if strings.HasPrefix(pubKeyUrl, prefix) {
+			trusted = true
+
+			break
+		}
+	}
+
+	if !trusted {
+		return nil, fmt.Errorf("不受信任的公钥地址：%s", pubKeyUrl)
+	}
+
+	if v, ok := ossCallbackPubKeyCache.Load(pubKeyUrl); ok {
+		return v.(*rsa.PublicKey), nil
+	}
+
+	resp, err := ossCallbackHttpClient.Get(pubKeyUrl)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取公钥失败，状态码：%d", resp.StatusCode)
+	}
+
+	bytes, err := io.ReadAll(io.LimitReader(resp.Body, ossCallbackPubKeyMaxSize))
+
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(bytes)
+
+	if block == nil {
+		return nil, errors.New("公钥不是合法的PEM格式")
+	}
+
+	if publicKey, err := x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+		return nil, err
+	} else if rsaPublicKey, ok := publicKey.(*rsa.PublicKey); !ok {
+		return nil, errors.New("公钥不是RSA公钥")
+	} else {
+		ossCallbackPubKeyCache.Store(pubKeyUrl, rsaPublicKey)
+
+		return rsaPublicKey, nil
+	}
+}
@@ -0,0 +1,106 @@
+package object
+
+import (
+	"crypto"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func Test_verifyOssCallbackSignature(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKeyBytes, _ := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	requests := int32(0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		pem.Encode(w, &pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyBytes})
+	}))
+
+	defer server.Close()
+
+	prefixes := ossCallbackPubKeyUrlPrefixes
+	ossCallbackPubKeyUrlPrefixes = []string{server.URL + "/"}
+
+	defer func() { ossCallbackPubKeyUrlPrefixes = prefixes }()
+
+	body := []byte("fileId=1&bucket=test&object=images%2Fa.png&etag=%22ABC%22&size=5&mimeType=image%2Fpng")
+	digest := md5.Sum(append([]byte("/v1/files/callback/oss?from=oss\n"), body...))
+	signature, _ := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.MD5, digest[:])
+
+	request := OssCallbackRequest{
+		PubKeyUrl:     base64.StdEncoding.EncodeToString([]byte(server.URL + "/callback_pub_key_v1.pem")),
+		Authorization: base64.StdEncoding.EncodeToString(signature),
+		Path:          "/v1/files/callback/oss",
+		RawQuery:      "from=oss",
+		Body:          body,
+	}
+
+	if err := verifyOssCallbackSignature(request); err != nil {
+		t.Fatalf("expected valid signature, got %v", err)
+	}
+
+	if err := verifyOssCallbackSignature(request); err != nil || atomic.LoadInt32(&requests) != 1 {
+		t.Errorf("expected cached public key, got %v after %d requests", err, requests)
+	}
+
+	tampered := request
+	tampered.Body = []byte("fileId=2&bucket=test&object=images%2Fa.png&etag=%22ABC%22&size=5&mimeType=image%2Fpng")
+
+	if err := verifyOssCallbackSignature(tampered); err == nil {
+		t.Error("expected error for tampered body")
+	}
+
+	untrusted := request
+	untrusted.PubKeyUrl = base64.StdEncoding.EncodeToString([]byte("http://example.com/callback_pub_key_v1.pem"))
+
+	if err := verifyOssCallbackSignature(untrusted); err == nil {
+		t.Error("expected error for untrusted public key url")
+	}
+
+	ossCallbackPubKeyUrlPrefixes = []string{"https://gosspublic.alicdn.com/"}
+
+	if _, err := getOssCallbackPublicKey(server.URL + "/callback_pub_key_v1.pem"); err == nil {
+		t.Error("expected error for http public key url")
+	}
+}
+
+func Test_verifyOssCallbackToken(t *testing.T) {
+	config := Config
+	Config = viper.New()
+
+	defer func() { Config = config }()
+
+	if verifyOssCallbackToken(1, "a.png", newOssCallbackToken(1, "a.png")) {
+		t.Error("expected error without callback secret")
+	}
+
+	Config.Set("file.oss-callback-secret", "secret")
+
+	body, _ := url.ParseQuery(strings.NewReplacer("${", "", "}", "").Replace(newOssCallback(1, "a.png").Body))
+
+	if !verifyOssCallbackToken(1, "a.png", body.Get("token")) {
+		t.Errorf("expected valid token, got %s", body.Get("token"))
+	}
+
+	if verifyOssCallbackToken(2, "a.png", body.Get("token")) || verifyOssCallbackToken(1, "b.png", body.Get("token")) {
+		t.Error("expected error for other file")
+	}
+}
@@ -10,7 +10,15 @@ type OssUploadFile struct {
 	SourceFileAttr    string         `json:"sourceFileAttr" form:"sourceFileAttr"`       //源文件属性
 	UseSourceFilename int            `json:"useSourceFilename" form:"useSourceFilename"` //是否使用源文件名
 	ExpiredInSec      int64          `json:"expiredInSec" form:"expiredInSec"`           //过期秒数
+	Callback          int            `json:"callback" form:"callback"`                   //是否启用上传回调
+	UploadMode        int            `json:"uploadMode" form:"uploadMode"`               //上传方式，0：表单POST；1：预签名PUT（存储空间限制文件大小时不可用）
+	ContentType       string         `json:"contentType" form:"contentType"`             //内容类型
+	ContentMD5        string         `json:"contentMD5" form:"contentMD5"`               //内容MD5（Base64编码）
+	Sha256            string         `json:"sha256" form:"sha256"`                       //内容SHA-256（十六进制）
+	ChallengeToken    string         `json:"challengeToken" form:"challengeToken"`       //秒传挑战令牌
+	ChallengeHash     string         `json:"challengeHash" form:"challengeHash"`         //挑战字节范围的SHA-256（十六进制）
 	ProcessParams     []ProcessParam `json:"processParams"`                              //处理参数
+	UploadConfig      *UploadConfig  `json:"uploadConfig"`                               //上传限制
 	ProcessParamsStr  string         `form:"processParams"`                              //处理参数
 }
 
@@ -1,11 +1,16 @@
 package object
 
+// 表单POST上传时，存储服务用秘钥ID查找秘钥校验策略签名，秘钥ID必须随表单提交（OSS V1在OSSAccessKeyId中，
+// V4和S3在凭证字段中），因此无法对浏览器隐藏；不希望暴露长期秘钥ID时应改用STS临时凭证上传
 type OssUploadToken struct {
-	FileId      int64  `json:"fileId"`      //文件ID
-	UploadUrl   string `json:"uploadUrl"`   //上传地址
-	AccessKeyId string `json:"accessKeyId"` //OSS秘钥ID
-	Policy      string `json:"policy"`      //策略
-	Signature   string `json:"signature"`   //签名
-	Key         string `json:"key"`         //文件键值
-	Url         string `json:"url"`         //文件地址
+	FileId      int64             `json:"fileId"`      //文件ID
+	Method      string            `json:"method"`      //上传请求方法
+	UploadUrl   string            `json:"uploadUrl"`   //上传地址
+	AccessKeyId string            `json:"accessKeyId"` //已废弃，保留兼容旧客户端，请使用fields中的表单字段；预签名PUT上传时为空
+	Policy      string            `json:"policy"`      //策略
+	Signature   string            `json:"signature"`   //签名
+	Key         string            `json:"key"`         //文件键值
+	Url         string            `json:"url"`         //文件地址
+	Fields      map[string]string `json:"fields"`      //表单字段
+	Headers     map[string]string `json:"headers"`     //上传请求头
 }
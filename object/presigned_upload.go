@@ -0,0 +1,134 @@
+package object
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/easynet-cn/file-service/log"
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/storage"
+	"github.com/easynet-cn/winter"
+	"go.uber.org/zap"
+)
+
+type PresignedPart struct {
+	PartNumber int    `json:"partNumber"` //分片号
+	Size       int64  `json:"size"`       //分片大小
+	Url        string `json:"url"`        //上传地址
+}
+
+type PresignedUploadSession struct {
+	UploadSession
+	Parts []PresignedPart `json:"parts"` //分片上传地址
+}
+
+func InitiatePresignedUpload(param InitiateUploadParam) (*PresignedUploadSession, error) {
+	if param.SourceFileSize <= 0 {
+		return nil, winter.NewBadRequestBusinessError("源文件大小不能为空")
+	}
+
+	engine := GetDB()
+
+	entity, bucketEntity, backend, err := initiateUploadSession(engine, param, uploadTypePresigned, "")
+
+	if err != nil {
+		return nil, err
+	}
+
+	parts, err := presignUploadParts(*entity, *bucketEntity, backend, nil, param.ExpiredInSec)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &PresignedUploadSession{UploadSession: *uploadSessionEntityToObject(*entity, *bucketEntity), Parts: parts}, nil
+}
+
+func PresignUploadParts(uploadId string, param PresignUploadPartsParam) ([]PresignedPart, error) {
+	engine := GetDB()
+
+	entity, bucketEntity, backend, err := getUploadingSession(engine, uploadId, accessWrite, uploadTypePresigned)
+
+	if err != nil {
+		return nil, err
+	}
+
+	partNumbers := param.PartNumbers
+
+	if len(partNumbers) == 0 {
+		if uploadedParts, err := backend.ListParts(bucketEntity.Name, entity.FileKey, entity.StorageUploadId); err != nil {
+			return nil, err
+		} else {
+			uploaded := make(map[int]bool)
+
+			for _, part := range uploadedParts {
+				uploaded[part.PartNumber] = true
+			}
+
+			partNumbers = make([]int, 0)
+
+			for partNumber := 1; partNumber <= getUploadPartCount(*entity); partNumber++ {
+				if !uploaded[partNumber] {
+					partNumbers = append(partNumbers, partNumber)
+				}
+			}
+		}
+	}
+
+	return presignUploadParts(*entity, *bucketEntity, backend, partNumbers, param.ExpiredInSec)
+}
+
+func presignUploadParts(entity repository.UploadSession, bucketEntity repository.Bucket, backend storage.Backend, partNumbers []int, expiredInSec int64) ([]PresignedPart, error) {
+	partCount := getUploadPartCount(entity)
+
+	if partNumbers == nil {
+		partNumbers = make([]int, partCount)
+
+		for i := range partNumbers {
+			partNumbers[i] = i + 1
+		}
+	}
+
+	if expiredInSec <= 0 {
+		expiredInSec = int64(60 * 60)
+	}
+
+	parts := make([]PresignedPart, 0, len(partNumbers))
+
+	for _, partNumber := range partNumbers {
+		if partNumber < 1 || partNumber > partCount {
+			return nil, winter.NewBadRequestBusinessError(fmt.Sprintf("分片号必须在1到%d之间", partCount))
+		}
+
+		signOptions := storage.SignOptions{
+			Method:       http.MethodPut,
+			ExpiredInSec: expiredInSec,
+			Domain:       bucketEntity.Domain,
+			Params: map[string]string{
+				"uploadId":   entity.StorageUploadId,
+				"partNumber": strconv.Itoa(partNumber),
+			},
+		}
+
+		if signedURL, err := backend.SignURL(bucketEntity.Name, entity.FileKey, signOptions); err != nil {
+			log.Logger.Error("backend.SignURL", zap.String("uploadId", entity.UploadId), zap.Int("partNumber", partNumber), zap.Error(err))
+
+			return nil, err
+		} else {
+			size := entity.PartSize
+
+			if partNumber == partCount {
+				size = entity.SourceFileSize - int64(partCount-1)*entity.PartSize
+			}
+
+			parts = append(parts, PresignedPart{PartNumber: partNumber, Size: size, Url: signedURL})
+		}
+	}
+
+	return parts, nil
+}
+
+func getUploadPartCount(entity repository.UploadSession) int {
+	return int((entity.SourceFileSize + entity.PartSize - 1) / entity.PartSize)
+}
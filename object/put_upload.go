@@ -0,0 +1,99 @@
+package object
+
+import (
+	"encoding/base64"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/storage"
+	"github.com/easynet-cn/winter"
+)
+
+const (
+	uploadModePost = 0
+	uploadModePut  = 1
+)
+
+// 预签名PUT无法像POST策略那样限制内容长度，存储空间限制了文件大小时不允许PUT上传；
+// 内容类型、MD5等请求头绑定到签名中，客户端必须原样携带
+func getPutUploadHeaders(provider string, uploadFile OssUploadFile, uploadConfig *UploadConfig) (map[string]string, error) {
+	if uploadConfig.MaxSize > 0 || uploadConfig.MinSize > 0 {
+		return nil, winter.NewBadRequestBusinessError("存储空间限制了文件大小，不支持PUT上传，请使用POST上传")
+	}
+
+	headers := make(map[string]string)
+	contentType := uploadFile.ContentType
+
+	if contentType == "" {
+		contentType = uploadConfig.ContentType
+	}
+
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(uploadFile.SourceFile))
+	}
+
+	if (uploadConfig.ContentType != "" && contentType != uploadConfig.ContentType) || !strings.HasPrefix(contentType, uploadConfig.ContentTypePrefix) {
+		return nil, winter.NewBadRequestBusinessError("内容类型不在存储空间允许的范围内")
+	}
+
+	if contentType != "" {
+		headers["Content-Type"] = contentType
+	}
+
+	if uploadFile.ContentMD5 != "" {
+		if bytes, err := base64.StdEncoding.DecodeString(uploadFile.ContentMD5); err != nil || len(bytes) != 16 {
+			return nil, winter.NewBadRequestBusinessError("内容MD5必须是Base64编码的16字节摘要")
+		}
+
+		headers["Content-MD5"] = uploadFile.ContentMD5
+	}
+
+	if uploadConfig.ContentDisposition != "" {
+		headers["Content-Disposition"] = uploadConfig.ContentDisposition
+	}
+
+	metaPrefix := "x-oss-meta-"
+
+	if provider == storage.ProviderS3 {
+		metaPrefix = "x-amz-meta-"
+	}
+
+	for k, v := range uploadConfig.Meta {
+		headers[metaPrefix+k] = v
+	}
+
+	return headers, nil
+}
+
+func getPutUploadToken(backend storage.Backend, bucketEntity repository.Bucket, fileId int64, fileKey string, headers map[string]string, callback *storage.Callback, expiredInSec int64, processParams []ProcessParam) (*OssUploadToken, error) {
+	if callback != nil {
+		if v, err := callback.Encode(); err != nil {
+			return nil, err
+		} else {
+			headers["x-oss-callback"] = v
+		}
+	}
+
+	uploadUrl, err := backend.SignURL(bucketEntity.Name, fileKey, storage.SignOptions{
+		Method:       http.MethodPut,
+		ExpiredInSec: expiredInSec,
+		Domain:       bucketEntity.Domain,
+		Headers:      headers,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &OssUploadToken{
+		FileId:    fileId,
+		Method:    http.MethodPut,
+		UploadUrl: uploadUrl,
+		Key:       fileKey,
+		Headers:   headers,
+		Url:       getUrl(backend, bucketEntity, fileKey, expiredInSec, processParams),
+	}, nil
+}
@@ -0,0 +1,282 @@
+package object
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/storage"
+	"github.com/easynet-cn/winter"
+)
+
+const (
+	selfTestProbePrefix     = ".file-service-selftest/"
+	selfTestUrlExpiredInSec = 300
+	selfTestTimeout         = 10 * time.Second
+)
+
+var (
+	selfTestProbeContent = []byte("file-service self-test")
+)
+
+type SelfTestStep struct {
+	Name     string `json:"name"`             //检查项，endpoint：端点；client：客户端；bucket：存储空间；put：上传；head：查询；sign：访问地址；delete：删除
+	Target   string `json:"target,omitempty"` //检查对象
+	Success  bool   `json:"success"`          //是否成功
+	Skipped  bool   `json:"skipped"`          //是否因前置检查失败而跳过
+	Message  string `json:"message"`          //结果说明或错误信息
+	Duration int64  `json:"duration"`         //耗时（毫秒）
+}
+
+type SelfTestReport struct {
+	AppId      int64          `json:"appId"`      //应用ID
+	BucketId   int64          `json:"bucketId"`   //存储空间ID，应用自检时为0
+	BucketName string         `json:"bucketName"` //存储空间名称
+	Success    bool           `json:"success"`    //是否全部成功
+	Steps      []SelfTestStep `json:"steps"`      //检查步骤
+}
+
+type selfTestRunner struct {
+	report *SelfTestReport
+	failed bool
+}
+
+// 前置检查失败时跳过
+func (r *selfTestRunner) step(name string, target string, f func() (string, error)) bool {
+	if r.failed {
+		r.report.Steps = append(r.report.Steps, SelfTestStep{Name: name, Target: target, Skipped: true, Message: "前置检查失败，已跳过"})
+
+		return false
+	}
+
+	return r.always(name, target, f)
+}
+
+// 无论前置检查是否失败都执行，用于清理探测对象和相互独立的检查
+func (r *selfTestRunner) always(name string, target string, f func() (string, error)) bool {
+	step := SelfTestStep{Name: name, Target: target}
+	start := time.Now()
+	message, err := f()
+
+	step.Duration = time.Since(start).Milliseconds()
+
+	if err != nil {
+		step.Message = err.Error()
+		r.failed = true
+	} else {
+		step.Success = true
+		step.Message = message
+	}
+
+	r.report.Steps = append(r.report.Steps, step)
+
+	return step.Success
+}
+
+func (r *selfTestRunner) finish() *SelfTestReport {
+	r.report.Success = !r.failed
+
+	return r.report
+}
+
+// TestApp 检查应用端点和访问秘钥，并检查应用下每个存储空间是否存在，不写入对象
+func TestApp(id int64) (*SelfTestReport, error) {
+	engine := GetDB()
+
+	if appEntity, err := repository.AppRepository.FindById(engine, id); err != nil {
+		return nil, err
+	} else if appEntity.Id == 0 {
+		return nil, winter.NewNotFoundBusinessError("应用不存在")
+	} else if bucketEntities, err := repository.BucketRepository.FindByAppId(engine, id); err != nil {
+		return nil, err
+	} else {
+		return runAppSelfTest(*appEntity, bucketEntities), nil
+	}
+}
+
+// TestBucket 使用探测对象完整检查上传、查询、访问地址和删除
+func TestBucket(id int64) (*SelfTestReport, error) {
+	engine := GetDB()
+
+	if bucketEntity, err := repository.BucketRepository.FindById(engine, id); err != nil {
+		return nil, err
+	} else if bucketEntity.Id == 0 {
+		return nil, winter.NewNotFoundBusinessError("存储空间不存在")
+	} else if appEntity, err := repository.AppRepository.FindById(engine, bucketEntity.AppId); err != nil {
+		return nil, err
+	} else if appEntity.Id == 0 {
+		return nil, winter.NewNotFoundBusinessError("应用不存在")
+	} else {
+		return runBucketSelfTest(*appEntity, *bucketEntity), nil
+	}
+}
+
+func runAppSelfTest(appEntity repository.App, bucketEntities []repository.Bucket) *SelfTestReport {
+	r := &selfTestRunner{report: &SelfTestReport{AppId: appEntity.Id, Steps: make([]SelfTestStep, 0)}}
+	backend := selfTestClient(r, appEntity)
+
+	for _, bucketEntity := range bucketEntities {
+		if backend == nil {
+			r.step("bucket", bucketEntity.Name, nil)
+		} else {
+			r.always("bucket", bucketEntity.Name, func() (string, error) { return checkSelfTestBucket(backend, bucketEntity.Name) })
+		}
+	}
+
+	return r.finish()
+}
+
+func runBucketSelfTest(appEntity repository.App, bucketEntity repository.Bucket) *SelfTestReport {
+	r := &selfTestRunner{report: &SelfTestReport{AppId: appEntity.Id, BucketId: bucketEntity.Id, BucketName: bucketEntity.Name, Steps: make([]SelfTestStep, 0)}}
+	backend := selfTestClient(r, appEntity)
+	key := selfTestProbePrefix + newSelfTestProbeId()
+
+	r.step("bucket", bucketEntity.Name, func() (string, error) { return checkSelfTestBucket(backend, bucketEntity.Name) })
+
+	put := r.step("put", key, func() (string, error) {
+		return "已上传探测对象", backend.Put(bucketEntity.Name, key, bytes.NewReader(selfTestProbeContent), storage.PutOptions{ContentType: "text/plain"})
+	})
+
+	r.step("head", key, func() (string, error) {
+		if objectInfo, err := backend.Head(bucketEntity.Name, key); err != nil {
+			return "", err
+		} else if objectInfo.Size != int64(len(selfTestProbeContent)) {
+			return "", fmt.Errorf("对象大小不一致，期望%d，实际%d", len(selfTestProbeContent), objectInfo.Size)
+		} else {
+			return "对象大小" + strconv.FormatInt(objectInfo.Size, 10), nil
+		}
+	})
+
+	r.step("sign", key, func() (string, error) {
+		return checkSelfTestUrl(getUrl(backend, bucketEntity, key, selfTestUrlExpiredInSec, nil))
+	})
+
+	if put {
+		r.always("delete", key, func() (string, error) {
+			if err := backend.Delete(bucketEntity.Name, key); err != nil {
+				return "", err
+			} else if _, err := backend.Head(bucketEntity.Name, key); !errors.Is(err, storage.ErrObjectNotFound) {
+				return "", fmt.Errorf("删除后对象仍然存在：%v", err)
+			} else {
+				return "已删除探测对象", nil
+			}
+		})
+	}
+
+	return r.finish()
+}
+
+// 检查端点并创建客户端，使用新建的客户端而不是缓存，以反映当前配置
+func selfTestClient(r *selfTestRunner, appEntity repository.App) storage.Backend {
+	var backend storage.Backend
+
+	r.step("endpoint", appEntity.Endpoint, func() (string, error) { return checkSelfTestEndpoint(appEntity) })
+	r.step("client", appEntity.Provider, func() (string, error) {
+		config, err := AppToStorageConfig(appEntity)
+
+		if err != nil {
+			return "", err
+		}
+
+		backend, err = storage.New(config)
+
+		return "已创建客户端", err
+	})
+
+	return backend
+}
+
+func checkSelfTestEndpoint(appEntity repository.App) (string, error) {
+	if appEntity.Provider == storage.ProviderLocal {
+		if info, err := os.Stat(appEntity.Endpoint); err != nil {
+			return "", err
+		} else if !info.IsDir() {
+			return "", errors.New("本地存储端点不是目录")
+		} else {
+			return "本地目录存在", nil
+		}
+	}
+
+	endpoint := appEntity.Endpoint
+
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+
+	u, err := url.Parse(endpoint)
+
+	if err != nil || u.Hostname() == "" {
+		return "", errors.New("端点格式不正确")
+	} else if net.ParseIP(u.Hostname()) != nil {
+		return u.Hostname(), nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), selfTestTimeout)
+
+	defer cancel()
+
+	if addrs, err := net.DefaultResolver.LookupHost(ctx, u.Hostname()); err != nil {
+		return "", fmt.Errorf("端点解析失败：%w", err)
+	} else {
+		return "解析到" + strings.Join(addrs, ","), nil
+	}
+}
+
+func checkSelfTestBucket(backend storage.Backend, bucketName string) (string, error) {
+	if _, err := backend.List(bucketName, selfTestProbePrefix, "", 1); err != nil {
+		return "", err
+	}
+
+	return "存储空间可访问", nil
+}
+
+// 访问生成的地址并比对内容，可发现域名配置错误；本地存储的相对地址无法在服务端访问，只校验生成
+func checkSelfTestUrl(rawUrl string) (string, error) {
+	if rawUrl == "" {
+		return "", errors.New("生成访问地址失败，请检查存储空间类型和签名配置")
+	} else if strings.HasPrefix(rawUrl, "/") && !strings.HasPrefix(rawUrl, "//") {
+		return "已生成相对地址，未验证访问", nil
+	} else if strings.HasPrefix(rawUrl, "//") {
+		rawUrl = "https:" + rawUrl
+	}
+
+	client := &http.Client{Timeout: selfTestTimeout}
+
+	resp, err := client.Get(rawUrl)
+
+	if err != nil {
+		return "", fmt.Errorf("访问地址失败：%w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("访问地址返回状态码%d", resp.StatusCode)
+	} else if body, err := io.ReadAll(io.LimitReader(resp.Body, int64(len(selfTestProbeContent))+1)); err != nil {
+		return "", err
+	} else if !bytes.Equal(body, selfTestProbeContent) {
+		return "", errors.New("访问地址返回内容与探测对象不一致")
+	}
+
+	return "访问地址可用", nil
+}
+
+func newSelfTestProbeId() string {
+	buf := make([]byte, 8)
+
+	rand.Read(buf)
+
+	return strconv.FormatInt(time.Now().Unix(), 10) + "-" + hex.EncodeToString(buf)
+}
@@ -0,0 +1,84 @@
+package object
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/storage"
+)
+
+func Test_runBucketSelfTest(t *testing.T) {
+	root := t.TempDir()
+	server := httptest.NewTLSServer(http.FileServer(http.Dir(filepath.Join(root, "public"))))
+
+	defer server.Close()
+
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = server.Client().Transport
+
+	defer func() { http.DefaultTransport = defaultTransport }()
+
+	appEntity := repository.App{Id: 1, Provider: storage.ProviderLocal, Endpoint: root, AccessKeySecret: "secret"}
+
+	for name, c := range map[string]struct {
+		bucket  repository.Bucket
+		success bool
+		failed  string
+	}{
+		"private":    {repository.Bucket{Id: 1, Name: "private", BucketType: 2}, true, ""},
+		"public":     {repository.Bucket{Id: 2, Name: "public", BucketType: 1, Domain: strings.TrimPrefix(server.URL, "https://")}, true, ""},
+		"bad-domain": {repository.Bucket{Id: 3, Name: "other", BucketType: 1, Domain: strings.TrimPrefix(server.URL, "https://")}, false, "sign"},
+		"no-type":    {repository.Bucket{Id: 4, Name: "none"}, false, "sign"},
+	} {
+		report := runBucketSelfTest(appEntity, c.bucket)
+
+		if report.Success != c.success {
+			t.Errorf("%s: expected success %v, got %+v", name, c.success, report.Steps)
+		}
+
+		names := make([]string, 0, len(report.Steps))
+
+		for _, step := range report.Steps {
+			names = append(names, step.Name)
+
+			if !step.Success && step.Name != c.failed {
+				t.Errorf("%s: unexpected failed step %+v", name, step)
+			}
+		}
+
+		if strings.Join(names, ",") != "endpoint,client,bucket,put,head,sign,delete" {
+			t.Errorf("%s: unexpected steps %v", name, names)
+		}
+
+		if entries, _ := os.ReadDir(filepath.Join(root, c.bucket.Name, strings.TrimSuffix(selfTestProbePrefix, "/"))); len(entries) != 0 {
+			t.Errorf("%s: expected probe object deleted", name)
+		}
+	}
+}
+
+func Test_runAppSelfTest(t *testing.T) {
+	buckets := []repository.Bucket{{Name: "a"}, {Name: "b"}}
+
+	if report := runAppSelfTest(repository.App{Provider: storage.ProviderLocal, Endpoint: t.TempDir(), AccessKeySecret: "secret"}, buckets); !report.Success || len(report.Steps) != 4 {
+		t.Errorf("expected success, got %+v", report)
+	}
+
+	report := runAppSelfTest(repository.App{Provider: storage.ProviderOss, Endpoint: "oss.selftest.invalid"}, buckets)
+
+	if report.Success || len(report.Steps) != 4 {
+		t.Fatalf("expected failure, got %+v", report)
+	} else if step := report.Steps[0]; step.Name != "endpoint" || step.Success || step.Skipped {
+		t.Errorf("expected endpoint failed, got %+v", step)
+	}
+
+	for _, step := range report.Steps[1:] {
+		if !step.Skipped {
+			t.Errorf("expected step skipped, got %+v", step)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package object
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"hash/crc64"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/easynet-cn/file-service/log"
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/storage"
+	"github.com/easynet-cn/winter"
+	"go.uber.org/zap"
+	"xorm.io/xorm"
+)
+
+var (
+	streamUploadPartSize = defaultUploadPartSize
+	streamUploadBufPool  = &sync.Pool{
+		New: func() any {
+			buf := make([]byte, streamUploadPartSize)
+
+			return &buf
+		},
+	}
+)
+
+type streamUploadOptions struct {
+	ContentType string //内容类型
+	ContentMD5  string //客户端提供的内容MD5（Base64编码）
+	Sha256      string //客户端提供的内容SHA-256（十六进制）
+}
+
+type streamUploadResult struct {
+	Size   int64
+	Sha256 string
+	Md5    string
+	Crc64  string
+}
+
+type streamDigest struct {
+	sha256 hash.Hash
+	md5    hash.Hash
+	crc64  hash.Hash64
+	writer io.Writer
+}
+
+func newStreamDigest() *streamDigest {
+	d := &streamDigest{sha256: sha256.New(), md5: md5.New(), crc64: crc64.New(crc64.MakeTable(crc64.ECMA))}
+
+	d.writer = io.MultiWriter(d.sha256, d.md5, d.crc64)
+
+	return d
+}
+
+// 在提交对象之前比对客户端提供的摘要，不一致时不会生成对象
+func (d *streamDigest) verify(options streamUploadOptions) error {
+	if options.ContentMD5 != "" && options.ContentMD5 != base64.StdEncoding.EncodeToString(d.md5.Sum(nil)) {
+		return winter.NewBadRequestBusinessError("文件MD5校验失败")
+	} else if options.Sha256 != "" && !strings.EqualFold(options.Sha256, hex.EncodeToString(d.sha256.Sum(nil))) {
+		return winter.NewBadRequestBusinessError("文件SHA-256校验失败")
+	}
+
+	return nil
+}
+
+func (d *streamDigest) result(size int64) *streamUploadResult {
+	return &streamUploadResult{
+		Size:   size,
+		Sha256: hex.EncodeToString(d.sha256.Sum(nil)),
+		Md5:    hex.EncodeToString(d.md5.Sum(nil)),
+		Crc64:  strconv.FormatUint(d.crc64.Sum64(), 10),
+	}
+}
+
+// 流式上传：每次最多缓冲一个分片，不足一个分片时直接Put，否则走分片上传，上传过程中同时计算摘要
+func putStream(backend storage.Backend, bucketName string, fileKey string, reader io.Reader, options streamUploadOptions) (*streamUploadResult, error) {
+	bufPtr := streamUploadBufPool.Get().(*[]byte)
+
+	defer streamUploadBufPool.Put(bufPtr)
+
+	buf := (*bufPtr)[:streamUploadPartSize]
+	putOptions := storage.PutOptions{ContentType: options.ContentType}
+	digest := newStreamDigest()
+
+	n, err := io.ReadFull(reader, buf)
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		digest.writer.Write(buf[:n])
+
+		if err := digest.verify(options); err != nil {
+			return nil, err
+		}
+
+		putOptions.ContentMD5 = base64.StdEncoding.EncodeToString(digest.md5.Sum(nil))
+
+		if err := backend.Put(bucketName, fileKey, bytes.NewReader(buf[:n]), putOptions); err != nil {
+			return nil, err
+		}
+
+		return verifyStoredObject(backend, bucketName, fileKey, digest.result(int64(n)))
+	} else if err != nil {
+		return nil, streamReadError(err)
+	}
+
+	uploadId, err := backend.InitiateMultipartUpload(bucketName, fileKey, putOptions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]storage.Part, 0)
+	size := int64(0)
+
+	for n > 0 {
+		if len(parts) >= maxUploadPartCount {
+			err = winter.NewBadRequestBusinessError("上传文件过大")
+
+			break
+		}
+
+		part, uploadErr := backend.UploadPart(bucketName, fileKey, uploadId, len(parts)+1, bytes.NewReader(buf[:n]), int64(n))
+
+		if uploadErr != nil {
+			err = uploadErr
+
+			break
+		}
+
+		parts = append(parts, *part)
+		size += int64(n)
+
+		digest.writer.Write(buf[:n])
+
+		if n, err = io.ReadFull(reader, buf); errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			err = nil
+		} else if err != nil {
+			err = streamReadError(err)
+
+			break
+		}
+	}
+
+	if err == nil {
+		err = digest.verify(options)
+	}
+
+	if err == nil {
+		err = backend.CompleteMultipartUpload(bucketName, fileKey, uploadId, parts)
+	}
+
+	if err != nil {
+		if abortErr := backend.AbortMultipartUpload(bucketName, fileKey, uploadId); abortErr != nil {
+			log.Logger.Error("backend.AbortMultipartUpload", zap.String("fileKey", fileKey), zap.String("uploadId", uploadId), zap.Error(abortErr))
+		}
+
+		return nil, err
+	}
+
+	return verifyStoredObject(backend, bucketName, fileKey, digest.result(size))
+}
+
+// 比对存储中对象的大小和CRC64（存储提供时），不一致时删除对象
+func verifyStoredObject(backend storage.Backend, bucketName string, fileKey string, result *streamUploadResult) (*streamUploadResult, error) {
+	objectInfo, err := backend.Head(bucketName, fileKey)
+
+	if err != nil {
+		return nil, err
+	} else if objectInfo.Size == result.Size && (objectInfo.Crc64 == "" || objectInfo.Crc64 == result.Crc64) {
+		return result, nil
+	}
+
+	log.Logger.Error("存储对象校验失败", zap.String("fileKey", fileKey), zap.Any("objectInfo", objectInfo), zap.Any("result", result))
+
+	if err := backend.Delete(bucketName, fileKey); err != nil {
+		log.Logger.Error("backend.Delete", zap.String("fileKey", fileKey), zap.Error(err))
+	}
+
+	return nil, errors.New("存储对象校验失败")
+}
+
+func streamReadError(err error) error {
+	var corruptInputError base64.CorruptInputError
+
+	if errors.As(err, &corruptInputError) {
+		return winter.NewBadRequestBusinessError("Base64数据不合法")
+	}
+
+	return err
+}
+
+// 存储空间启用去重时，查找内容相同的有效文件并复用其对象，命中时已增加对象引用数；指定了文件key或使用源文件名时不去重
+func findDedupFile(engine *xorm.Engine, bucketEntity repository.Bucket, backend storage.Backend, uploadFile OssUploadFile, sha256 string, size int64) *repository.File {
+	if entity := findDedupCandidate(engine, bucketEntity, backend, uploadFile, sha256, size); entity == nil {
+		return nil
+	} else if !attachFileObject(engine, bucketEntity.Id, entity.FileKey) {
+		return nil
+	} else {
+		return entity
+	}
+}
+
+// 查找可复用的文件，不增加对象引用数
+func findDedupCandidate(engine *xorm.Engine, bucketEntity repository.Bucket, backend storage.Backend, uploadFile OssUploadFile, sha256 string, size int64) *repository.File {
+	if bucketEntity.Dedup != 1 || uploadFile.FileKey != "" || uploadFile.UseSourceFilename == 1 {
+		return nil
+	}
+
+	if entity, err := repository.FileRepository.FindActiveByBucketIdAndSha256(engine, bucketEntity.Id, sha256, size); err != nil {
+		log.Logger.Error("repository.FindActiveFileBySha256", zap.String("sha256", sha256), zap.Error(err))
+
+		return nil
+	} else if entity.Id == 0 {
+		return nil
+	} else if _, err := backend.Head(bucketEntity.Name, entity.FileKey); err != nil {
+		log.Logger.Warn("去重文件对象不可用", zap.Int64("fileId", entity.Id), zap.String("fileKey", entity.FileKey), zap.Error(err))
+
+		return nil
+	} else {
+		return entity
+	}
+}
@@ -0,0 +1,77 @@
+package object
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash/crc64"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/easynet-cn/file-service/storage"
+	"github.com/easynet-cn/winter"
+)
+
+func Test_putStream(t *testing.T) {
+	backend, err := storage.NewLocalBackend(storage.Config{Provider: storage.ProviderLocal, Endpoint: t.TempDir(), AccessKeySecret: "secret"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	partSize := streamUploadPartSize
+	streamUploadPartSize = 4
+
+	defer func() { streamUploadPartSize = partSize }()
+
+	for key, content := range map[string]string{"a.txt": "abc", "b.txt": "abcd", "c.txt": "abcdefghij"} {
+		sha256Sum := sha256.Sum256([]byte(content))
+		md5Sum := md5.Sum([]byte(content))
+		crc64Sum := strconv.FormatUint(crc64.Checksum([]byte(content), crc64.MakeTable(crc64.ECMA)), 10)
+		options := streamUploadOptions{ContentType: "text/plain", ContentMD5: base64.StdEncoding.EncodeToString(md5Sum[:]), Sha256: strings.ToUpper(hex.EncodeToString(sha256Sum[:]))}
+
+		if result, err := putStream(backend, "test", key, strings.NewReader(content), options); err != nil {
+			t.Fatal(err)
+		} else if result.Size != int64(len(content)) || result.Sha256 != hex.EncodeToString(sha256Sum[:]) || result.Md5 != hex.EncodeToString(md5Sum[:]) || result.Crc64 != crc64Sum {
+			t.Errorf("unexpected result for %s: %+v", key, result)
+		} else if reader, err := backend.Get("test", key); err != nil {
+			t.Fatal(err)
+		} else {
+			bytes, _ := io.ReadAll(reader)
+
+			reader.Close()
+
+			if string(bytes) != content {
+				t.Errorf("unexpected content for %s: %s", key, bytes)
+			}
+		}
+	}
+
+	md5Sum := md5.Sum([]byte("other"))
+
+	for key, options := range map[string]streamUploadOptions{
+		"e.txt": {ContentMD5: base64.StdEncoding.EncodeToString(md5Sum[:])},
+		"f.txt": {Sha256: strings.Repeat("0", 64)},
+	} {
+		for _, content := range []string{"abc", "abcdefghij"} {
+			if _, err := putStream(backend, "test", key, strings.NewReader(content), options); err == nil {
+				t.Errorf("expected digest mismatch for %s", key)
+			} else if _, err := backend.Head("test", key); err != storage.ErrObjectNotFound {
+				t.Errorf("expected no object after digest mismatch, got %v", err)
+			}
+		}
+	}
+
+	reader := base64.NewDecoder(base64.StdEncoding, strings.NewReader("aGVsbG8gd29ybGQ!!!!"))
+
+	if _, err := putStream(backend, "test", "d.txt", reader, streamUploadOptions{}); err == nil {
+		t.Error("expected error for corrupt base64 data")
+	} else if businessError, ok := err.(*winter.BusinessError); !ok || businessError.Status != 400 {
+		t.Errorf("expected bad request, got %v", err)
+	} else if _, err := backend.Head("test", "d.txt"); err != storage.ErrObjectNotFound {
+		t.Errorf("expected aborted upload, got %v", err)
+	}
+}
@@ -0,0 +1,325 @@
+package object
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dromara/carbon/v2"
+	"github.com/easynet-cn/file-service/log"
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/storage"
+	"github.com/easynet-cn/winter"
+	"go.uber.org/zap"
+	"xorm.io/xorm"
+)
+
+const (
+	TusVersion              = "1.0.0"
+	TusExtension            = "creation,termination,expiration"
+	TusMaxSize              = maxUploadPartCount * tusMaxPartSize
+	tusMaxPartSize          = int64(32 * 1024 * 1024)
+	defaultTusStagingPrefix = ".file-service-tus/"
+	tusStagingListSize      = 1000
+)
+
+var (
+	// 每个PATCH需要缓存一个分片，限制分片大小并复用缓冲区
+	tusBufferPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+)
+
+func CreateTusUpload(uploadLength int64, metadata map[string]string) (*UploadSession, error) {
+	engine := GetDB()
+
+	if metadata["bucket"] == "" {
+		return nil, winter.NewBadRequestBusinessError("Upload-Metadata缺少bucket")
+	}
+
+	sourceFile := metadata["filename"]
+
+	if sourceFile == "" {
+		sourceFile = metadata["name"]
+	}
+
+	param := InitiateUploadParam{
+		OssUploadFile: OssUploadFile{
+			Bucket:         metadata["bucket"],
+			Prefix:         metadata["prefix"],
+			FileKey:        metadata["fileKey"],
+			SourceFile:     sourceFile,
+			SourceFileSize: uploadLength,
+			SourceFileType: strings.TrimPrefix(filepath.Ext(sourceFile), "."),
+			SourceFileAttr: metadata["sourceFileAttr"],
+		},
+	}
+
+	contentType := metadata["filetype"]
+
+	if contentType == "" {
+		contentType = metadata["type"]
+	}
+
+	if bucketEntity, err := repository.BucketRepository.FindByName(engine, param.Bucket); err != nil {
+		return nil, err
+	} else if bucketEntity.Id > 0 {
+		if _, err := getTusStagingBucket(*bucketEntity); err != nil {
+			return nil, err
+		}
+	}
+
+	entity, bucketEntity, backend, err := initiateUploadSession(engine, param, uploadTypeTus, contentType)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if uploadLength == 0 {
+		if _, err := completeUploadSession(engine, entity, *bucketEntity, backend); err != nil {
+			return nil, err
+		}
+	}
+
+	return uploadSessionEntityToObject(*entity, *bucketEntity), nil
+}
+
+func GetTusUpload(uploadId string) (*UploadSession, error) {
+	engine := GetDB()
+
+	if entity, err := repository.UploadSessionRepository.FindByUploadId(engine, uploadId); err != nil {
+		return nil, err
+	} else if entity.Id == 0 || entity.UploadType != uploadTypeTus || entity.Status == uploadSessionStatusAborted {
+		return nil, winter.NewNotFoundBusinessError("上传会话不存在")
+	} else if entity.Status == uploadSessionStatusUploading && carbon.Parse(entity.ExpireTime).Lt(carbon.Now()) {
+		return nil, winter.NewNotFoundBusinessError("上传会话已过期")
+	} else if bucketEntity, err := repository.BucketRepository.FindById(engine, entity.BucketId); err != nil {
+		return nil, err
+	} else if bucketEntity.Id == 0 {
+		return nil, winter.NewNotFoundBusinessError("存储空间不存在")
+	} else {
+		return uploadSessionEntityToObject(*entity, *bucketEntity), nil
+	}
+}
+
+// 请求体按分片大小切分上传，不足一个分片的尾部数据按偏移量暂存为临时对象，下次PATCH时与新数据拼接；
+// 并发PATCH各自写入不同的暂存对象，只有更新偏移量成功的请求的暂存数据会被读取
+func PatchTusUpload(uploadId string, uploadOffset int64, reader io.Reader) (*UploadSession, error) {
+	engine := GetDB()
+
+	entity, bucketEntity, backend, err := getUploadingSession(engine, uploadId, accessWrite, uploadTypeTus)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if uploadOffset != entity.UploadOffset {
+		return nil, winter.NewBusinessError(http.StatusConflict, "409", fmt.Sprintf("Upload-Offset应为%d", entity.UploadOffset))
+	}
+
+	// 上次完成失败时数据已全部上传，没有暂存数据，直接重试完成
+	if entity.UploadOffset == entity.SourceFileSize {
+		return completeTusUpload(engine, entity, *bucketEntity, backend)
+	}
+
+	partOffset := entity.UploadOffset - entity.UploadOffset%entity.PartSize
+	buf := tusBufferPool.Get().(*bytes.Buffer)
+	hasTail := partOffset < entity.UploadOffset
+
+	buf.Reset()
+	buf.Grow(int(entity.PartSize))
+
+	defer tusBufferPool.Put(buf)
+
+	if hasTail {
+		if err := readTusTail(*entity, *bucketEntity, backend, buf); err != nil {
+			return nil, err
+		}
+	}
+
+	reader = io.LimitReader(reader, entity.SourceFileSize-entity.UploadOffset)
+
+	for {
+		_, readErr := io.CopyN(buf, reader, entity.PartSize-int64(buf.Len()))
+
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			log.Logger.Warn("读取tus上传数据中断", zap.String("uploadId", uploadId), zap.Int64("uploadOffset", partOffset+int64(buf.Len())), zap.Error(readErr))
+		}
+
+		if int64(buf.Len()) == entity.PartSize || (readErr != nil && partOffset+int64(buf.Len()) == entity.SourceFileSize && buf.Len() > 0) {
+			partNumber := int(partOffset/entity.PartSize) + 1
+
+			if _, err := backend.UploadPart(bucketEntity.Name, entity.FileKey, entity.StorageUploadId, partNumber, bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+				log.Logger.Error("backend.UploadPart", zap.String("uploadId", uploadId), zap.Int("partNumber", partNumber), zap.Error(err))
+
+				return nil, err
+			}
+
+			partOffset += int64(buf.Len())
+
+			buf.Reset()
+
+			if err := updateTusUploadOffset(engine, entity, partOffset); err != nil {
+				return nil, err
+			}
+		}
+
+		if readErr != nil {
+			break
+		}
+	}
+
+	if entity.UploadOffset == entity.SourceFileSize {
+		return completeTusUpload(engine, entity, *bucketEntity, backend)
+	} else if tailOffset := partOffset + int64(buf.Len()); buf.Len() > 0 && tailOffset > entity.UploadOffset {
+		if stagingBucket, err := getTusStagingBucket(*bucketEntity); err != nil {
+			return nil, err
+		} else if err := backend.Put(stagingBucket, tusTailKey(*entity, tailOffset), bytes.NewReader(buf.Bytes()), storage.PutOptions{}); err != nil {
+			log.Logger.Error("backend.Put", zap.String("uploadId", uploadId), zap.Error(err))
+
+			return nil, err
+		} else if err := updateTusUploadOffset(engine, entity, tailOffset); err != nil {
+			deleteTusTail(*entity, *bucketEntity, backend, tailOffset)
+
+			return nil, err
+		}
+	}
+
+	if hasTail && entity.UploadOffset != uploadOffset {
+		deleteTusTail(*entity, *bucketEntity, backend, uploadOffset)
+	}
+
+	return uploadSessionEntityToObject(*entity, *bucketEntity), nil
+}
+
+func completeTusUpload(engine *xorm.Engine, entity *repository.UploadSession, bucketEntity repository.Bucket, backend storage.Backend) (*UploadSession, error) {
+	if _, err := completeUploadSession(engine, entity, bucketEntity, backend); err != nil {
+		return nil, err
+	}
+
+	deleteTusTails(*entity, bucketEntity, backend)
+
+	return uploadSessionEntityToObject(*entity, bucketEntity), nil
+}
+
+func TerminateTusUpload(uploadId string) error {
+	engine := GetDB()
+
+	if entity, bucketEntity, backend, err := getUploadingSession(engine, uploadId, accessWrite, uploadTypeTus); err != nil {
+		return err
+	} else {
+		return abortUploadSession(engine, *entity, *bucketEntity, backend)
+	}
+}
+
+func ParseTusMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+
+		if pair == "" {
+			continue
+		}
+
+		key, value, _ := strings.Cut(pair, " ")
+
+		if bytes, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value)); err != nil {
+			return nil, fmt.Errorf("Upload-Metadata中%s的值不是合法的Base64：%w", key, err)
+		} else {
+			metadata[key] = string(bytes)
+		}
+	}
+
+	return metadata, nil
+}
+
+func updateTusUploadOffset(engine *xorm.Engine, entity *repository.UploadSession, uploadOffset int64) error {
+	previousOffset := entity.UploadOffset
+
+	entity.UploadOffset = uploadOffset
+	entity.UpdateTime = carbon.Now().ToDateTimeString()
+
+	if affected, err := repository.UploadSessionRepository.UpdateUploadOffset(engine, entity, previousOffset); err != nil {
+		return err
+	} else if affected == 0 {
+		return winter.NewBusinessError(http.StatusConflict, "409", "上传会话正在被其他请求写入")
+	}
+
+	return nil
+}
+
+func readTusTail(entity repository.UploadSession, bucketEntity repository.Bucket, backend storage.Backend, buf *bytes.Buffer) error {
+	tailSize := entity.UploadOffset % entity.PartSize
+
+	if stagingBucket, err := getTusStagingBucket(bucketEntity); err != nil {
+		return err
+	} else if reader, err := backend.Get(stagingBucket, tusTailKey(entity, entity.UploadOffset)); err != nil {
+		log.Logger.Error("backend.Get", zap.String("uploadId", entity.UploadId), zap.Error(err))
+
+		return err
+	} else {
+		defer reader.Close()
+
+		if n, err := io.Copy(buf, io.LimitReader(reader, tailSize)); err != nil {
+			return err
+		} else if n != tailSize {
+			return fmt.Errorf("tus暂存数据不完整，期望%d字节，实际%d字节", tailSize, n)
+		}
+	}
+
+	return nil
+}
+
+func deleteTusTail(entity repository.UploadSession, bucketEntity repository.Bucket, backend storage.Backend, uploadOffset int64) {
+	if stagingBucket, err := getTusStagingBucket(bucketEntity); err != nil {
+		log.Logger.Error("getTusStagingBucket", zap.String("uploadId", entity.UploadId), zap.Error(err))
+	} else if err := backend.Delete(stagingBucket, tusTailKey(entity, uploadOffset)); err != nil {
+		log.Logger.Error("backend.Delete", zap.String("uploadId", entity.UploadId), zap.Error(err))
+	}
+}
+
+// 分页删除会话的全部暂存对象，包括并发PATCH失败后残留的
+func deleteTusTails(entity repository.UploadSession, bucketEntity repository.Bucket, backend storage.Backend) {
+	stagingBucket, err := getTusStagingBucket(bucketEntity)
+
+	if err != nil {
+		log.Logger.Error("getTusStagingBucket", zap.String("uploadId", entity.UploadId), zap.Error(err))
+
+		return
+	}
+
+	prefix := getTusStagingPrefix() + entity.UploadId + "/"
+	marker := ""
+
+	for {
+		result, err := backend.List(stagingBucket, prefix, marker, tusStagingListSize)
+
+		if err != nil {
+			log.Logger.Error("backend.List", zap.String("uploadId", entity.UploadId), zap.Error(err))
+
+			return
+		}
+
+		for _, objectInfo := range result.Objects {
+			if err := backend.Delete(stagingBucket, objectInfo.Key); err != nil {
+				log.Logger.Error("backend.Delete", zap.String("uploadId", entity.UploadId), zap.Error(err))
+			}
+		}
+
+		if !result.IsTruncated || result.NextMarker == "" {
+			return
+		}
+
+		marker = result.NextMarker
+	}
+}
+
+func tusTailKey(entity repository.UploadSession, uploadOffset int64) string {
+	return getTusStagingPrefix() + entity.UploadId + "/" + strconv.FormatInt(uploadOffset, 10)
+}
@@ -0,0 +1,155 @@
+package object
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/storage"
+)
+
+func TestParseTusMetadata(t *testing.T) {
+	metadata, err := ParseTusMetadata("bucket dGVzdA==,filename bW92aWUubXA0, is_confidential")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if metadata["bucket"] != "test" || metadata["filename"] != "movie.mp4" {
+		t.Errorf("unexpected metadata: %v", metadata)
+	}
+
+	if v, ok := metadata["is_confidential"]; !ok || v != "" {
+		t.Errorf("expected empty value for key without value, got %q", v)
+	}
+
+	if _, err := ParseTusMetadata("bucket !!!"); err == nil {
+		t.Error("expected error for invalid base64")
+	}
+}
+
+func Test_getUploadPartSize(t *testing.T) {
+	if partSize, err := getUploadPartSize(0, 1024); err != nil || partSize != defaultUploadPartSize {
+		t.Errorf("unexpected default part size: %d %v", partSize, err)
+	}
+
+	if _, err := getUploadPartSize(1024, 1024); err == nil {
+		t.Error("expected error for part size below minimum")
+	}
+
+	if partSize, err := getUploadPartSize(0, 100*1024*1024*1024); err != nil || partSize*maxUploadPartCount < 100*1024*1024*1024 {
+		t.Errorf("part size %d cannot hold file: %v", partSize, err)
+	}
+}
+
+type failingCompleteBackend struct {
+	storage.Backend
+	failures int
+}
+
+func (b *failingCompleteBackend) CompleteMultipartUpload(bucket string, key string, uploadId string, parts []storage.Part) error {
+	if b.failures > 0 {
+		b.failures--
+
+		return errors.New("complete failed")
+	}
+
+	return b.Backend.CompleteMultipartUpload(bucket, key, uploadId, parts)
+}
+
+func TestPatchTusUpload_retryComplete(t *testing.T) {
+	engine, appEntity := setupTestDB(t)
+
+	createTestBucket(t, engine, repository.Bucket{AppId: appEntity.Id, BucketType: 2, Name: "tus"})
+
+	localBackend, err := getBackendByApp(appEntity)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := &failingCompleteBackend{Backend: localBackend, failures: 1}
+
+	backendCache.Store(appEntity.Id, &backendCacheEntry{key: newAppBackendKey(appEntity), backend: backend})
+
+	content := "hello tus"
+	session, err := CreateTusUpload(int64(len(content)), map[string]string{"bucket": "tus", "filename": "a.txt"})
+
+	if err != nil {
+		t.Fatal(err)
+	} else if int64(len(content))%session.PartSize == 0 {
+		t.Fatalf("expected size not a multiple of part size %d", session.PartSize)
+	}
+
+	if _, err := PatchTusUpload(session.UploadId, 0, strings.NewReader(content)); err == nil {
+		t.Fatal("expected first completion failed")
+	}
+
+	if m, err := GetTusUpload(session.UploadId); err != nil || m.UploadOffset != int64(len(content)) {
+		t.Fatalf("expected offset at size after failed completion, got %+v %v", m, err)
+	}
+
+	if m, err := PatchTusUpload(session.UploadId, int64(len(content)), strings.NewReader("")); err != nil {
+		t.Fatalf("expected retry completed, got %v", err)
+	} else if m.FileId == 0 {
+		t.Errorf("expected file created, got %+v", m)
+	} else if reader, err := localBackend.Get("tus", m.FileKey); err != nil {
+		t.Fatal(err)
+	} else {
+		bytes, _ := io.ReadAll(reader)
+
+		reader.Close()
+
+		if string(bytes) != content {
+			t.Errorf("unexpected content: %s", bytes)
+		}
+	}
+}
+
+func TestCreateTusUpload_publicBucket(t *testing.T) {
+	engine, appEntity := setupTestDB(t)
+
+	createTestBucket(t, engine, repository.Bucket{AppId: appEntity.Id, BucketType: 1, Name: "public"})
+
+	if _, err := CreateTusUpload(1024, map[string]string{"bucket": "public", "filename": "a.txt"}); err == nil {
+		t.Error("expected public bucket rejected without staging bucket")
+	}
+}
+
+type pagingListBackend struct {
+	storage.Backend
+}
+
+func (b *pagingListBackend) List(bucket string, prefix string, marker string, maxKeys int) (*storage.ListResult, error) {
+	return b.Backend.List(bucket, prefix, marker, 1)
+}
+
+func Test_deleteTusTails(t *testing.T) {
+	engine, appEntity := setupTestDB(t)
+
+	bucketEntity := createTestBucket(t, engine, repository.Bucket{AppId: appEntity.Id, BucketType: 2, Name: "tus"})
+
+	localBackend, err := getBackendByApp(appEntity)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entity := repository.UploadSession{UploadId: "u1"}
+
+	for _, offset := range []int64{1, 2, 3} {
+		if err := localBackend.Put("tus", tusTailKey(entity, offset), strings.NewReader("x"), storage.PutOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleteTusTails(entity, bucketEntity, &pagingListBackend{Backend: localBackend})
+
+	if result, err := localBackend.List("tus", getTusStagingPrefix()+entity.UploadId+"/", "", 1000); err != nil {
+		t.Fatal(err)
+	} else if len(result.Objects) != 0 {
+		t.Errorf("expected all tails deleted across pages, got %d", len(result.Objects))
+	}
+}
@@ -0,0 +1,144 @@
+package object
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/easynet-cn/file-service/storage"
+	"github.com/easynet-cn/winter"
+)
+
+var (
+	uploadMetaKeyRegexp       = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+	uploadSuccessActionStatus = map[string]bool{"200": true, "201": true, "204": true}
+)
+
+type UploadConfig struct {
+	MinSize             int64             `json:"minSize"`             //最小文件大小
+	MaxSize             int64             `json:"maxSize"`             //最大文件大小，0：不限制
+	ContentType         string            `json:"contentType"`         //内容类型
+	ContentTypePrefix   string            `json:"contentTypePrefix"`   //内容类型前缀
+	ContentDisposition  string            `json:"contentDisposition"`  //内容描述
+	SuccessActionStatus string            `json:"successActionStatus"` //上传成功返回的状态码
+	Meta                map[string]string `json:"meta"`                //自定义元数据
+}
+
+// 请求参数只能在存储空间配置的基础上收紧限制，不能放宽；存储空间已配置的内容描述和自定义元数据不能被请求覆盖
+func mergeUploadConfig(bucketConfig *UploadConfig, requestConfig *UploadConfig) (*UploadConfig, error) {
+	m := &UploadConfig{Meta: make(map[string]string)}
+
+	if bucketConfig != nil {
+		*m = *bucketConfig
+		m.Meta = make(map[string]string, len(bucketConfig.Meta))
+
+		for k, v := range bucketConfig.Meta {
+			m.Meta[k] = v
+		}
+	}
+
+	if requestConfig != nil {
+		if requestConfig.MinSize > m.MinSize {
+			m.MinSize = requestConfig.MinSize
+		}
+
+		if requestConfig.MaxSize > 0 {
+			if m.MaxSize > 0 && requestConfig.MaxSize > m.MaxSize {
+				return nil, winter.NewBadRequestBusinessError(fmt.Sprintf("最大文件大小不能超过%d", m.MaxSize))
+			}
+
+			m.MaxSize = requestConfig.MaxSize
+		}
+
+		if requestConfig.ContentType != "" {
+			if (m.ContentType != "" && requestConfig.ContentType != m.ContentType) || !strings.HasPrefix(requestConfig.ContentType, m.ContentTypePrefix) {
+				return nil, winter.NewBadRequestBusinessError("内容类型不在存储空间允许的范围内")
+			}
+
+			m.ContentType = requestConfig.ContentType
+		}
+
+		if requestConfig.ContentTypePrefix != "" {
+			if (m.ContentType != "" && !strings.HasPrefix(m.ContentType, requestConfig.ContentTypePrefix)) || !strings.HasPrefix(requestConfig.ContentTypePrefix, m.ContentTypePrefix) {
+				return nil, winter.NewBadRequestBusinessError("内容类型不在存储空间允许的范围内")
+			}
+
+			m.ContentTypePrefix = requestConfig.ContentTypePrefix
+		}
+
+		if requestConfig.ContentDisposition != "" && m.ContentDisposition == "" {
+			m.ContentDisposition = requestConfig.ContentDisposition
+		}
+
+		if requestConfig.SuccessActionStatus != "" {
+			m.SuccessActionStatus = requestConfig.SuccessActionStatus
+		}
+
+		for k, v := range requestConfig.Meta {
+			if _, ok := m.Meta[k]; !ok {
+				m.Meta[k] = v
+			}
+		}
+	}
+
+	if m.MaxSize > 0 && m.MinSize > m.MaxSize {
+		return nil, winter.NewBadRequestBusinessError("最小文件大小不能超过最大文件大小")
+	}
+
+	if m.SuccessActionStatus != "" && !uploadSuccessActionStatus[m.SuccessActionStatus] {
+		return nil, winter.NewBadRequestBusinessError("上传成功状态码只能是200、201或204")
+	}
+
+	for k := range m.Meta {
+		if !uploadMetaKeyRegexp.MatchString(k) {
+			return nil, winter.NewBadRequestBusinessError(fmt.Sprintf("自定义元数据名称不合法：%s", k))
+		}
+	}
+
+	return m, nil
+}
+
+func parseUploadConfig(s string) *UploadConfig {
+	if s == "" {
+		return nil
+	}
+
+	uploadConfig := &UploadConfig{}
+
+	if err := json.Unmarshal([]byte(s), uploadConfig); err != nil {
+		return nil
+	}
+
+	return uploadConfig
+}
+
+func checkUploadContentType(uploadConfig *UploadConfig, contentType string) error {
+	if (uploadConfig.ContentType != "" && contentType != uploadConfig.ContentType) || !strings.HasPrefix(contentType, uploadConfig.ContentTypePrefix) {
+		return winter.NewBadRequestBusinessError("内容类型不在存储空间允许的范围内")
+	}
+
+	return nil
+}
+
+func checkUploadFileSize(uploadConfig *UploadConfig, size int64) error {
+	if size > 0 && uploadConfig.MaxSize > 0 && size > uploadConfig.MaxSize {
+		return winter.NewBadRequestBusinessError(fmt.Sprintf("文件大小不能超过%d", uploadConfig.MaxSize))
+	} else if size > 0 && size < uploadConfig.MinSize {
+		return winter.NewBadRequestBusinessError(fmt.Sprintf("文件大小不能小于%d", uploadConfig.MinSize))
+	}
+
+	return nil
+}
+
+func uploadConfigToPostPolicyOptions(uploadConfig *UploadConfig, options storage.PostPolicyOptions) storage.PostPolicyOptions {
+	options.MinSize = uploadConfig.MinSize
+	options.MaxSize = uploadConfig.MaxSize
+	options.ContentType = uploadConfig.ContentType
+	options.ContentTypePrefix = uploadConfig.ContentTypePrefix
+	options.ContentDisposition = uploadConfig.ContentDisposition
+	options.SuccessActionStatus = uploadConfig.SuccessActionStatus
+	options.Meta = uploadConfig.Meta
+
+	return options
+}
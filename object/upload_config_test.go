@@ -0,0 +1,49 @@
+package object
+
+import "testing"
+
+func Test_mergeUploadConfig(t *testing.T) {
+	bucketConfig := &UploadConfig{MaxSize: 10 * 1024 * 1024, ContentTypePrefix: "image/", Meta: map[string]string{"source": "web"}}
+
+	if m, err := mergeUploadConfig(bucketConfig, nil); err != nil || m.MaxSize != bucketConfig.MaxSize || m.ContentTypePrefix != "image/" {
+		t.Errorf("unexpected bucket defaults: %+v %v", m, err)
+	}
+
+	m, err := mergeUploadConfig(bucketConfig, &UploadConfig{MaxSize: 1024, ContentType: "image/png", Meta: map[string]string{"owner": "alice"}})
+
+	if err != nil {
+		t.Fatal(err)
+	} else if m.MaxSize != 1024 || m.ContentType != "image/png" || m.Meta["source"] != "web" || m.Meta["owner"] != "alice" {
+		t.Errorf("unexpected merged config: %+v", m)
+	}
+
+	if bucketConfig.Meta["owner"] != "" {
+		t.Error("bucket config should not be modified")
+	}
+
+	if m, err := mergeUploadConfig(&UploadConfig{ContentDisposition: "attachment", Meta: map[string]string{"source": "web"}}, &UploadConfig{ContentDisposition: "inline", Meta: map[string]string{"source": "app"}}); err != nil {
+		t.Fatal(err)
+	} else if m.ContentDisposition != "attachment" || m.Meta["source"] != "web" {
+		t.Errorf("expected bucket content disposition and meta kept, got %+v", m)
+	}
+
+	if _, err := mergeUploadConfig(bucketConfig, &UploadConfig{MaxSize: 20 * 1024 * 1024}); err == nil {
+		t.Error("expected error when request loosens max size")
+	}
+
+	if _, err := mergeUploadConfig(bucketConfig, &UploadConfig{ContentType: "application/x-msdownload"}); err == nil {
+		t.Error("expected error when request loosens content type")
+	}
+
+	if _, err := mergeUploadConfig(nil, &UploadConfig{SuccessActionStatus: "302"}); err == nil {
+		t.Error("expected error for invalid success action status")
+	}
+
+	if _, err := mergeUploadConfig(nil, &UploadConfig{Meta: map[string]string{"a b": "c"}}); err == nil {
+		t.Error("expected error for invalid meta key")
+	}
+
+	if err := checkUploadFileSize(m, 2048); err == nil {
+		t.Error("expected error for oversized file")
+	}
+}
@@ -0,0 +1,110 @@
+package object
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/easynet-cn/file-service/log"
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/storage"
+	"github.com/easynet-cn/winter"
+	"go.uber.org/zap"
+)
+
+const (
+	minCredentialsExpiredInSec     = 900
+	defaultCredentialsExpiredInSec = 3600
+	credentialsSessionName         = "file-service"
+)
+
+var (
+	credentialsActions = []string{
+		"oss:PutObject",
+		"oss:InitiateMultipartUpload",
+		"oss:UploadPart",
+		"oss:UploadPartCopy",
+		"oss:CompleteMultipartUpload",
+		"oss:AbortMultipartUpload",
+		"oss:ListParts",
+	}
+)
+
+type UploadCredentials struct {
+	storage.Credentials
+	Bucket   string `json:"bucket"`   //存储空间名称
+	Endpoint string `json:"endpoint"` //端点
+	Region   string `json:"region"`   //区域
+	Prefix   string `json:"prefix"`   //允许写入的文件前缀
+}
+
+func GetUploadCredentials(uploadFile OssUploadFile) (*UploadCredentials, error) {
+	engine := GetDB()
+	prefix := strings.Trim(uploadFile.Prefix, "/")
+
+	if prefix == "" {
+		return nil, winter.NewBadRequestBusinessError("临时凭证必须指定文件前缀")
+	} else if strings.ContainsAny(prefix, "*?$") || strings.Contains(prefix, "..") {
+		return nil, winter.NewBadRequestBusinessError("文件前缀不合法")
+	}
+
+	expiredInSec := int64(defaultCredentialsExpiredInSec)
+
+	if uploadFile.ExpiredInSec > 0 {
+		expiredInSec = max(min(uploadFile.ExpiredInSec, defaultCredentialsExpiredInSec), minCredentialsExpiredInSec)
+	}
+
+	if bucketEntity, err := repository.BucketRepository.FindByName(engine, uploadFile.Bucket); err != nil {
+		return nil, err
+	} else if bucketEntity.Id == 0 {
+		return nil, winter.NewBadRequestBusinessError("存储空间不存在")
+	} else if appEntity, err := repository.AppRepository.FindById(engine, bucketEntity.AppId); err != nil {
+		return nil, err
+	} else if appEntity.Id == 0 {
+		return nil, winter.NewBadRequestBusinessError("应用不存在")
+	} else if err := checkAccess(*appEntity, *bucketEntity, accessWrite); err != nil {
+		return nil, err
+	} else if appEntity.RoleArn == "" {
+		return nil, winter.NewBadRequestBusinessError("应用未配置STS角色")
+	} else if config, err := AppToStorageConfig(*appEntity); err != nil {
+		return nil, err
+	} else if provider, err := storage.NewCredentialProvider(config); errors.Is(err, storage.ErrCredentialsNotSupported) {
+		return nil, winter.NewBadRequestBusinessError(err.Error())
+	} else if err != nil {
+		return nil, err
+	} else if policy, err := getUploadCredentialsPolicy(bucketEntity.Name, prefix); err != nil {
+		return nil, err
+	} else if credentials, err := provider.AssumeRole(storage.AssumeRoleOptions{SessionName: credentialsSessionName, Policy: policy, DurationSeconds: expiredInSec}); err != nil {
+		log.Logger.Error("provider.AssumeRole", zap.Int64("appId", appEntity.Id), zap.String("bucketName", bucketEntity.Name), zap.Error(err))
+
+		return nil, err
+	} else {
+		return &UploadCredentials{
+			Credentials: *credentials,
+			Bucket:      bucketEntity.Name,
+			Endpoint:    appEntity.Endpoint,
+			Region:      appEntity.Region,
+			Prefix:      prefix + "/",
+		}, nil
+	}
+}
+
+// 临时凭证的权限是角色权限与该策略的交集，只允许向指定存储空间的前缀下写入
+func getUploadCredentialsPolicy(bucketName string, prefix string) (string, error) {
+	policy := map[string]any{
+		"Version": "1",
+		"Statement": []map[string]any{
+			{
+				"Effect":   "Allow",
+				"Action":   credentialsActions,
+				"Resource": []string{"acs:oss:*:*:" + bucketName + "/" + prefix + "/*"},
+			},
+		},
+	}
+
+	if bytes, err := json.Marshal(policy); err != nil {
+		return "", err
+	} else {
+		return string(bytes), nil
+	}
+}
@@ -0,0 +1,484 @@
+package object
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/dromara/carbon/v2"
+	"github.com/easynet-cn/file-service/log"
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/file-service/storage"
+	"github.com/easynet-cn/winter"
+	"go.uber.org/zap"
+	"xorm.io/xorm"
+)
+
+const (
+	defaultUploadPartSize         = int64(8 * 1024 * 1024)
+	minUploadPartSize             = int64(5 * 1024 * 1024)
+	maxUploadPartSize             = int64(5 * 1024 * 1024 * 1024)
+	maxUploadPartCount            = 10000
+	uploadSessionExpiredDays      = 7
+	cleanUploadSessionsBatchSize  = 500
+	uploadSessionStatusUploading  = 0
+	uploadSessionStatusCompleted  = 1
+	uploadSessionStatusAborted    = 2
+	uploadSessionStatusCompleting = 3
+	uploadSessionCompletingSecs   = 10 * 60
+	uploadTypeMultipart           = 0
+	uploadTypeTus                 = 1
+	uploadTypePresigned           = 2
+)
+
+type UploadSession struct {
+	UploadId       string `json:"uploadId"`       //上传ID
+	Bucket         string `json:"bucket"`         //bucket名称
+	FileKey        string `json:"fileKey"`        //文件key
+	SourceFile     string `json:"sourceFile"`     //源文件
+	SourceFileSize int64  `json:"sourceFileSize"` //源文件大小
+	SourceFileType string `json:"sourceFileType"` //源文件类型
+	SourceFileAttr string `json:"sourceFileAttr"` //源文件属性
+	PartSize       int64  `json:"partSize"`       //分片大小
+	UploadOffset   int64  `json:"uploadOffset"`   //已上传偏移量
+	FileId         int64  `json:"fileId"`         //文件ID
+	Status         int    `json:"status"`         //状态，0：上传中；1：已完成；2：已取消；3：完成中
+	ExpireTime     string `json:"expireTime"`     //过期时间
+}
+
+func InitiateUploadSession(param InitiateUploadParam) (*UploadSession, error) {
+	engine := GetDB()
+
+	if entity, bucketEntity, _, err := initiateUploadSession(engine, param, uploadTypeMultipart, ""); err != nil {
+		return nil, err
+	} else {
+		return uploadSessionEntityToObject(*entity, *bucketEntity), nil
+	}
+}
+
+func initiateUploadSession(engine *xorm.Engine, param InitiateUploadParam, uploadType int, contentType string) (*repository.UploadSession, *repository.Bucket, storage.Backend, error) {
+	bucketEntity, backend, err := getBackendByBucketName(engine, param.Bucket, accessWrite)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	partSize, err := getUploadPartSize(param.PartSize, param.SourceFileSize)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	fileKey := generateFileKey(param.OssUploadFile)
+
+	if param.SourceFileType == "" {
+		param.SourceFileType = strings.TrimPrefix(filepath.Ext(param.SourceFile), ".")
+	}
+
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(fileKey))
+	}
+
+	storageUploadId, err := backend.InitiateMultipartUpload(bucketEntity.Name, fileKey, storage.PutOptions{ContentType: contentType})
+
+	if err != nil {
+		log.Logger.Error("backend.InitiateMultipartUpload", zap.String("bucketName", bucketEntity.Name), zap.String("fileKey", fileKey), zap.Error(err))
+
+		return nil, nil, nil, err
+	}
+
+	now := carbon.Now()
+
+	entity := &repository.UploadSession{
+		UploadId:        winter.NewObjectID().Hex(),
+		BucketId:        bucketEntity.Id,
+		FileKey:         fileKey,
+		StorageUploadId: storageUploadId,
+		SourceFile:      param.SourceFile,
+		SourceFileSize:  param.SourceFileSize,
+		SourceFileType:  param.SourceFileType,
+		SourceFileAttr:  param.SourceFileAttr,
+		PartSize:        partSize,
+		UploadType:      uploadType,
+		Status:          uploadSessionStatusUploading,
+		ExpireTime:      now.Copy().AddDays(uploadSessionExpiredDays).ToDateTimeString(),
+		CreateTime:      now.ToDateTimeString(),
+		UpdateTime:      now.ToDateTimeString(),
+	}
+
+	if err := repository.UploadSessionRepository.Create(engine, entity); err != nil {
+		if err := backend.AbortMultipartUpload(bucketEntity.Name, fileKey, storageUploadId); err != nil {
+			log.Logger.Error("backend.AbortMultipartUpload", zap.String("bucketName", bucketEntity.Name), zap.String("fileKey", fileKey), zap.Error(err))
+		}
+
+		return nil, nil, nil, err
+	}
+
+	return entity, bucketEntity, backend, nil
+}
+
+func UploadSessionPart(uploadId string, partNumber int, reader io.Reader, size int64) (*storage.Part, error) {
+	engine := GetDB()
+
+	entity, bucketEntity, backend, err := getUploadingSession(engine, uploadId, accessWrite, uploadTypeMultipart, uploadTypePresigned)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if partNumber < 1 || partNumber > maxUploadPartCount {
+		return nil, winter.NewBadRequestBusinessError(fmt.Sprintf("分片号必须在1到%d之间", maxUploadPartCount))
+	} else if size <= 0 || size > entity.PartSize {
+		return nil, winter.NewBadRequestBusinessError(fmt.Sprintf("分片大小必须在1到%d字节之间", entity.PartSize))
+	} else if entity.SourceFileSize > 0 && int64(partNumber-1)*entity.PartSize >= entity.SourceFileSize {
+		return nil, winter.NewBadRequestBusinessError("分片号超出文件大小")
+	}
+
+	if part, err := backend.UploadPart(bucketEntity.Name, entity.FileKey, entity.StorageUploadId, partNumber, reader, size); err != nil {
+		log.Logger.Error("backend.UploadPart", zap.String("uploadId", uploadId), zap.Int("partNumber", partNumber), zap.Error(err))
+
+		return nil, err
+	} else {
+		return part, nil
+	}
+}
+
+func ListUploadSessionParts(uploadId string) ([]storage.Part, error) {
+	engine := GetDB()
+
+	if entity, bucketEntity, backend, err := getUploadingSession(engine, uploadId, accessRead, uploadTypeMultipart, uploadTypePresigned); err != nil {
+		return nil, err
+	} else {
+		return backend.ListParts(bucketEntity.Name, entity.FileKey, entity.StorageUploadId)
+	}
+}
+
+func CompleteUploadSession(uploadId string, param CompleteUploadParam) (*File, error) {
+	engine := GetDB()
+
+	entity, bucketEntity, backend, err := getUploadingSession(engine, uploadId, accessWrite, uploadTypeMultipart, uploadTypePresigned)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fileEntity, err := completeUploadSession(engine, entity, *bucketEntity, backend)
+
+	if err != nil {
+		return nil, err
+	}
+
+	expiredInSec := int64(60 * 60)
+
+	if param.ExpiredInSec > 0 {
+		expiredInSec = param.ExpiredInSec
+	}
+
+	return &File{
+		Id:             fileEntity.Id,
+		BucketId:       bucketEntity.Id,
+		BucketName:     bucketEntity.Name,
+		Domain:         bucketEntity.Domain,
+		FileKey:        fileEntity.FileKey,
+		SourceFile:     fileEntity.SourceFile,
+		SourceFileSize: fileEntity.SourceFileSize,
+		SourceFileType: fileEntity.SourceFileType,
+		SourceFileAttr: fileEntity.SourceFileAttr,
+		Url:            getUrl(backend, *bucketEntity, fileEntity.FileKey, expiredInSec, param.ProcessParams),
+		CreateTime:     fileEntity.CreateTime,
+		UpdateTime:     fileEntity.UpdateTime,
+	}, nil
+}
+
+// 先将会话置为完成中再合并分片，避免并发完成；失败时恢复为上传中以便重试
+func completeUploadSession(engine *xorm.Engine, entity *repository.UploadSession, bucketEntity repository.Bucket, backend storage.Backend) (*repository.File, error) {
+	if err := claimUploadSession(engine, entity); err != nil {
+		return nil, err
+	}
+
+	fileEntity, err := finishUploadSession(engine, entity, bucketEntity, backend)
+
+	if err != nil {
+		if err := updateUploadSessionStatus(engine, entity, uploadSessionStatusUploading); err != nil {
+			log.Logger.Error("updateUploadSessionStatus", zap.String("uploadId", entity.UploadId), zap.Error(err))
+		}
+
+		return nil, err
+	}
+
+	return fileEntity, nil
+}
+
+// 完成中的会话超时未结束时视为上次完成请求已中断，允许重新完成
+func claimUploadSession(engine *xorm.Engine, entity *repository.UploadSession) error {
+	if entity.Status == uploadSessionStatusCompleting && carbon.Parse(entity.UpdateTime).AddSeconds(uploadSessionCompletingSecs).Gt(carbon.Now()) {
+		return winter.NewBusinessError(http.StatusConflict, "409", "上传会话正在完成")
+	} else if err := updateUploadSessionStatus(engine, entity, uploadSessionStatusCompleting); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// 按读取时的状态和更新时间条件更新，被其他请求抢先更新时返回冲突
+func updateUploadSessionStatus(engine *xorm.Engine, entity *repository.UploadSession, status int) error {
+	previousStatus := entity.Status
+	previousUpdateTime := entity.UpdateTime
+
+	entity.Status = status
+	entity.UpdateTime = carbon.Now().ToDateTimeString()
+
+	if affected, err := repository.UploadSessionRepository.UpdateStatus(engine, entity, previousStatus, carbon.Parse(previousUpdateTime).ToDateTimeString()); err != nil {
+		entity.Status = previousStatus
+		entity.UpdateTime = previousUpdateTime
+
+		return err
+	} else if affected == 0 {
+		entity.Status = previousStatus
+		entity.UpdateTime = previousUpdateTime
+
+		return winter.NewBusinessError(http.StatusConflict, "409", "上传会话正在被其他请求处理")
+	}
+
+	return nil
+}
+
+func finishUploadSession(engine *xorm.Engine, entity *repository.UploadSession, bucketEntity repository.Bucket, backend storage.Backend) (*repository.File, error) {
+	size, retried, err := completeStorageUpload(entity, bucketEntity, backend)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fileEntity := &repository.File{}
+
+	// 重试时文件记录可能已在上次请求中创建
+	if retried {
+		if fileEntity, err = repository.FileRepository.FindByBucketIdAndFileKeyAndCreateTimeFrom(engine, bucketEntity.Id, entity.FileKey, carbon.Parse(entity.CreateTime).ToDateTimeString()); err != nil {
+			return nil, err
+		}
+	}
+
+	now := carbon.Now().ToDateTimeString()
+
+	if fileEntity.Id == 0 {
+		fileEntity = &repository.File{
+			BucketId:       bucketEntity.Id,
+			FileKey:        entity.FileKey,
+			SourceFile:     entity.SourceFile,
+			SourceFileSize: size,
+			SourceFileType: entity.SourceFileType,
+			SourceFileAttr: entity.SourceFileAttr,
+			CreateTime:     now,
+			UpdateTime:     now,
+		}
+
+		if err := repository.FileRepository.Create(engine, fileEntity); err != nil {
+			log.Logger.Error("repository.CreateFile", zap.Any("fileEntity", fileEntity), zap.Error(err))
+
+			return nil, err
+		}
+	}
+
+	entity.FileId = fileEntity.Id
+
+	if err := updateUploadSessionStatus(engine, entity, uploadSessionStatusCompleted); err != nil {
+		log.Logger.Error("updateUploadSessionStatus", zap.String("uploadId", entity.UploadId), zap.Error(err))
+
+		return nil, err
+	}
+
+	return fileEntity, nil
+}
+
+// 上次请求已合并分片但未完成记录时，分片上传已不存在，使用会话创建后写入的同名对象
+func completeStorageUpload(entity *repository.UploadSession, bucketEntity repository.Bucket, backend storage.Backend) (int64, bool, error) {
+	parts, err := backend.ListParts(bucketEntity.Name, entity.FileKey, entity.StorageUploadId)
+
+	if err != nil {
+		if objectInfo, headErr := backend.Head(bucketEntity.Name, entity.FileKey); headErr == nil && objectInfo.LastModified.Unix() >= carbon.Parse(entity.CreateTime).Timestamp() && (entity.SourceFileSize == 0 || objectInfo.Size == entity.SourceFileSize) {
+			return objectInfo.Size, true, nil
+		}
+
+		log.Logger.Error("backend.ListParts", zap.String("uploadId", entity.UploadId), zap.Error(err))
+
+		return 0, false, err
+	}
+
+	if len(parts) == 0 && entity.UploadType != uploadTypeTus {
+		return 0, false, winter.NewBadRequestBusinessError("尚未上传任何分片")
+	}
+
+	size := int64(0)
+
+	for i, part := range parts {
+		if part.PartNumber != i+1 {
+			return 0, false, winter.NewBadRequestBusinessError(fmt.Sprintf("缺少第%d个分片", i+1))
+		}
+
+		size += part.Size
+	}
+
+	if entity.SourceFileSize > 0 && size != entity.SourceFileSize {
+		return 0, false, winter.NewBadRequestBusinessError(fmt.Sprintf("已上传大小%d与文件大小%d不一致", size, entity.SourceFileSize))
+	}
+
+	if len(parts) > 0 {
+		if err := backend.CompleteMultipartUpload(bucketEntity.Name, entity.FileKey, entity.StorageUploadId, parts); err != nil {
+			log.Logger.Error("backend.CompleteMultipartUpload", zap.String("uploadId", entity.UploadId), zap.Error(err))
+
+			return 0, false, err
+		}
+	} else if err := backend.Put(bucketEntity.Name, entity.FileKey, strings.NewReader(""), storage.PutOptions{ContentType: mime.TypeByExtension(filepath.Ext(entity.FileKey))}); err != nil {
+		return 0, false, err
+	} else if err := backend.AbortMultipartUpload(bucketEntity.Name, entity.FileKey, entity.StorageUploadId); err != nil {
+		log.Logger.Error("backend.AbortMultipartUpload", zap.String("uploadId", entity.UploadId), zap.Error(err))
+	}
+
+	return size, false, nil
+}
+
+func AbortUploadSession(uploadId string) error {
+	engine := GetDB()
+
+	if entity, bucketEntity, backend, err := getUploadingSession(engine, uploadId, accessWrite, uploadTypeMultipart, uploadTypePresigned); err != nil {
+		return err
+	} else {
+		return abortUploadSession(engine, *entity, *bucketEntity, backend)
+	}
+}
+
+// 过期的上传中会话取消上传；完成中的会话超时未结束时说明完成请求所在进程已中断，此时可能已合并分片，重新完成而不是取消
+func CleanExpiredUploadSessions() {
+	engine := GetDB()
+	expireTime := carbon.Now().ToDateTimeString()
+	completingUpdateTime := carbon.Now().SubSeconds(uploadSessionCompletingSecs).ToDateTimeString()
+
+	for {
+		entities, err := repository.UploadSessionRepository.FindExpired(engine, expireTime, completingUpdateTime, cleanUploadSessionsBatchSize)
+
+		if err != nil {
+			log.Logger.Error("repository.FindExpiredUploadSessions", zap.Error(err))
+
+			return
+		}
+
+		cleaned := 0
+
+		for _, entity := range entities {
+			if bucketEntity, err := repository.BucketRepository.FindById(engine, entity.BucketId); err != nil {
+				log.Logger.Error("repository.FindBucketById", zap.Int64("bucketId", entity.BucketId), zap.Error(err))
+			} else if bucketEntity.Id == 0 {
+				if err := markUploadSessionAborted(engine, &entity); err == nil {
+					cleaned++
+				}
+			} else if backend, err := getBackendByBucket(engine, *bucketEntity, accessAny); err != nil {
+				log.Logger.Error("getBackendByBucket", zap.Int64("bucketId", bucketEntity.Id), zap.Error(err))
+			} else if entity.Status == uploadSessionStatusCompleting {
+				if _, err := completeUploadSession(engine, &entity, *bucketEntity, backend); err != nil {
+					log.Logger.Error("completeUploadSession", zap.String("uploadId", entity.UploadId), zap.Error(err))
+				} else {
+					if entity.UploadType == uploadTypeTus {
+						deleteTusTails(entity, *bucketEntity, backend)
+					}
+
+					cleaned++
+				}
+			} else if err := abortUploadSession(engine, entity, *bucketEntity, backend); err != nil {
+				log.Logger.Error("abortUploadSession", zap.String("uploadId", entity.UploadId), zap.Error(err))
+			} else {
+				cleaned++
+			}
+		}
+
+		if cleaned > 0 {
+			log.Logger.Info("清理过期上传会话", zap.Int("count", cleaned))
+		}
+
+		if len(entities) < cleanUploadSessionsBatchSize || cleaned == 0 {
+			return
+		}
+	}
+}
+
+func getUploadingSession(engine *xorm.Engine, uploadId string, mode accessMode, uploadTypes ...int) (*repository.UploadSession, *repository.Bucket, storage.Backend, error) {
+	if entity, err := repository.UploadSessionRepository.FindByUploadId(engine, uploadId); err != nil {
+		return nil, nil, nil, err
+	} else if entity.Id == 0 || !slices.Contains(uploadTypes, entity.UploadType) {
+		return nil, nil, nil, winter.NewNotFoundBusinessError("上传会话不存在")
+	} else if entity.Status == uploadSessionStatusCompleted {
+		return nil, nil, nil, winter.NewBadRequestBusinessError("上传会话已完成")
+	} else if entity.Status == uploadSessionStatusCompleting && carbon.Parse(entity.UpdateTime).AddSeconds(uploadSessionCompletingSecs).Gt(carbon.Now()) {
+		return nil, nil, nil, winter.NewBusinessError(http.StatusConflict, "409", "上传会话正在完成")
+	} else if entity.Status == uploadSessionStatusAborted || carbon.Parse(entity.ExpireTime).Lt(carbon.Now()) {
+		return nil, nil, nil, winter.NewNotFoundBusinessError("上传会话已取消或已过期")
+	} else if bucketEntity, err := repository.BucketRepository.FindById(engine, entity.BucketId); err != nil {
+		return nil, nil, nil, err
+	} else if bucketEntity.Id == 0 {
+		return nil, nil, nil, winter.NewNotFoundBusinessError("存储空间不存在")
+	} else if backend, err := getBackendByBucket(engine, *bucketEntity, mode); err != nil {
+		return nil, nil, nil, err
+	} else {
+		return entity, bucketEntity, backend, nil
+	}
+}
+
+func abortUploadSession(engine *xorm.Engine, entity repository.UploadSession, bucketEntity repository.Bucket, backend storage.Backend) error {
+	if err := backend.AbortMultipartUpload(bucketEntity.Name, entity.FileKey, entity.StorageUploadId); err != nil {
+		log.Logger.Error("backend.AbortMultipartUpload", zap.String("uploadId", entity.UploadId), zap.Error(err))
+
+		return err
+	}
+
+	if entity.UploadType == uploadTypeTus {
+		deleteTusTails(entity, bucketEntity, backend)
+	}
+
+	return markUploadSessionAborted(engine, &entity)
+}
+
+func markUploadSessionAborted(engine *xorm.Engine, entity *repository.UploadSession) error {
+	entity.Status = uploadSessionStatusAborted
+	entity.UpdateTime = carbon.Now().ToDateTimeString()
+
+	return repository.UploadSessionRepository.Update(engine, []string{"status", "update_time"}, entity)
+}
+
+func getUploadPartSize(partSize int64, fileSize int64) (int64, error) {
+	if partSize <= 0 {
+		partSize = defaultUploadPartSize
+	} else if partSize < minUploadPartSize || partSize > maxUploadPartSize {
+		return 0, winter.NewBadRequestBusinessError(fmt.Sprintf("分片大小必须在%d到%d字节之间", minUploadPartSize, maxUploadPartSize))
+	}
+
+	if minPartSize := (fileSize + maxUploadPartCount - 1) / maxUploadPartCount; partSize < minPartSize {
+		if minPartSize > maxUploadPartSize {
+			return 0, winter.NewBadRequestBusinessError("文件过大")
+		}
+
+		partSize = minPartSize
+	}
+
+	return partSize, nil
+}
+
+func uploadSessionEntityToObject(entity repository.UploadSession, bucketEntity repository.Bucket) *UploadSession {
+	return &UploadSession{
+		UploadId:       entity.UploadId,
+		Bucket:         bucketEntity.Name,
+		FileKey:        entity.FileKey,
+		SourceFile:     entity.SourceFile,
+		SourceFileSize: entity.SourceFileSize,
+		SourceFileType: entity.SourceFileType,
+		SourceFileAttr: entity.SourceFileAttr,
+		PartSize:       entity.PartSize,
+		UploadOffset:   entity.UploadOffset,
+		FileId:         entity.FileId,
+		Status:         entity.Status,
+		ExpireTime:     entity.ExpireTime,
+	}
+}
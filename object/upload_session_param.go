@@ -0,0 +1,16 @@
+package object
+
+type InitiateUploadParam struct {
+	OssUploadFile
+	PartSize int64 `json:"partSize"` //分片大小
+}
+
+type CompleteUploadParam struct {
+	ExpiredInSec  int64          `json:"expiredInSec"`  //过期秒数
+	ProcessParams []ProcessParam `json:"processParams"` //处理参数
+}
+
+type PresignUploadPartsParam struct {
+	PartNumbers  []int `json:"partNumbers"`  //分片号，为空时返回所有未上传分片
+	ExpiredInSec int64 `json:"expiredInSec"` //过期秒数
+}
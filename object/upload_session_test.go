@@ -0,0 +1,47 @@
+package object
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/easynet-cn/file-service/repository"
+)
+
+func TestCleanExpiredUploadSessions(t *testing.T) {
+	engine, appEntity := setupTestDB(t)
+
+	createTestBucket(t, engine, repository.Bucket{AppId: appEntity.Id, BucketType: 2, Name: "uploads"})
+
+	sessions := make(map[string]*UploadSession)
+
+	for _, name := range []string{"completing", "expired", "uploading"} {
+		session, err := InitiateUploadSession(InitiateUploadParam{OssUploadFile: OssUploadFile{Bucket: "uploads", SourceFile: name + ".txt", SourceFileSize: 5}})
+
+		if err != nil {
+			t.Fatal(err)
+		} else if _, err := UploadSessionPart(session.UploadId, 1, strings.NewReader("hello"), 5); err != nil {
+			t.Fatal(err)
+		}
+
+		sessions[name] = session
+	}
+
+	// 模拟完成请求所在进程中断，以及会话过期
+	if _, err := engine.Exec("UPDATE upload_session SET status=?, update_time=? WHERE upload_id=?", uploadSessionStatusCompleting, "2026-01-01 00:00:00", sessions["completing"].UploadId); err != nil {
+		t.Fatal(err)
+	} else if _, err := engine.Exec("UPDATE upload_session SET expire_time=? WHERE upload_id=?", "2026-01-01 00:00:00", sessions["expired"].UploadId); err != nil {
+		t.Fatal(err)
+	}
+
+	CleanExpiredUploadSessions()
+
+	for name, status := range map[string]int{"completing": uploadSessionStatusCompleted, "expired": uploadSessionStatusAborted, "uploading": uploadSessionStatusUploading} {
+		if entity, err := repository.UploadSessionRepository.FindByUploadId(engine, sessions[name].UploadId); err != nil {
+			t.Fatal(err)
+		} else if entity.Status != status {
+			t.Errorf("%s: expected status %d, got %d", name, status, entity.Status)
+		} else if status == uploadSessionStatusCompleted && entity.FileId == 0 {
+			t.Errorf("%s: expected file created", name)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/easynet-cn/file-service/log"
+	"github.com/easynet-cn/file-service/repository"
+	"github.com/easynet-cn/winter"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultUrlUploadMaxSize   = int64(100 * 1024 * 1024)
+	defaultUrlUploadTimeout   = 60 * time.Second
+	maxUrlUploadRedirectCount = 5
+)
+
+var (
+	errUrlUploadAddressBlocked = errors.New("不允许访问内网地址")
+	urlUploadBlockedNetworks   = parseCIDRs([]string{
+		"0.0.0.0/8",
+		"10.0.0.0/8",
+		"100.64.0.0/10",
+		"127.0.0.0/8",
+		"169.254.0.0/16",
+		"172.16.0.0/12",
+		"192.0.0.0/24",
+		"192.168.0.0/16",
+		"198.18.0.0/15",
+		"224.0.0.0/3",
+		"::/128",
+		"::1/128",
+		"fc00::/7",
+		"fe80::/10",
+		"ff00::/8",
+	})
+)
+
+type UploadUrlParam struct {
+	OssUploadFile
+	Url string `json:"url" form:"url" binding:"required"` //源文件地址
+}
+
+type urlFetcher struct {
+	client  *http.Client
+	maxSize int64
+}
+
+type urlFetchResult struct {
+	Body          *sizeLimitReadCloser
+	ContentType   string
+	ContentLength int64
+	Filename      string
+}
+
+func UploadFileFromUrl(param UploadUrlParam) (*File, error) {
+	engine := GetDB()
+
+	ossBucket, err := repository.BucketRepository.FindByName(engine, param.Bucket)
+
+	if err != nil || ossBucket.Id == 0 {
+		log.Logger.Error("repository.FindBucketByName", zap.String("bucketName", param.Bucket), zap.Error(err))
+
+		return nil, err
+	}
+
+	// 下载远程文件前先校验状态，避免无效下载
+	if appEntity, err := repository.AppRepository.FindById(engine, ossBucket.AppId); err != nil || appEntity.Id == 0 {
+		return nil, err
+	} else if err := checkAccess(*appEntity, *ossBucket, accessWrite); err != nil {
+		return nil, err
+	}
+
+	uploadConfig, err := mergeUploadConfig(parseUploadConfig(ossBucket.UploadConfig), param.UploadConfig)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fetcher := newUrlFetcherFromConfig()
+
+	if uploadConfig.MaxSize > 0 && uploadConfig.MaxSize < fetcher.maxSize {
+		fetcher.maxSize = uploadConfig.MaxSize
+	}
+
+	result, err := fetcher.fetch(param.Url)
+
+	if err != nil {
+		log.Logger.Warn("下载远程文件失败", zap.String("url", param.Url), zap.Error(err))
+
+		return nil, err
+	}
+
+	defer result.Body.Close()
+
+	contentType := param.ContentType
+
+	if contentType == "" {
+		contentType = result.ContentType
+	}
+
+	if err := checkUploadContentType(uploadConfig, contentType); err != nil {
+		return nil, err
+	} else if err := checkUploadFileSize(uploadConfig, result.ContentLength); err != nil {
+		return nil, err
+	}
+
+	uploadFile := param.OssUploadFile
+	uploadFile.ContentType = contentType
+
+	if uploadFile.SourceFile == "" {
+		uploadFile.SourceFile = result.Filename
+	}
+
+	if uploadFile.SourceFileType == "" {
+		uploadFile.SourceFileType = strings.TrimPrefix(path.Ext(uploadFile.SourceFile), ".")
+	}
+
+	result.Body.minSize = uploadConfig.MinSize
+
+	return UploadFile(uploadFile, result.Body)
+}
+
+func newUrlFetcherFromConfig() *urlFetcher {
+	maxSize := defaultUrlUploadMaxSize
+	timeout := defaultUrlUploadTimeout
+	allowedNetworks := make([]string, 0)
+
+	if Config != nil {
+		if v := Config.GetInt64("file.url-upload.max-size"); v > 0 {
+			maxSize = v
+		}
+
+		if v := Config.GetDuration("file.url-upload.timeout"); v > 0 {
+			timeout = v
+		}
+
+		allowedNetworks = Config.GetStringSlice("file.url-upload.allowed-networks")
+	}
+
+	return newUrlFetcher(maxSize, timeout, parseCIDRs(allowedNetworks))
+}
+
+// 在建立连接时校验解析后的IP，避免通过DNS重绑定或重定向访问内网地址
+func newUrlFetcher(maxSize int64, timeout time.Duration, allowedNetworks []*net.IPNet) *urlFetcher {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network string, address string, c syscall.RawConn) error {
+			if host, _, err := net.SplitHostPort(address); err != nil {
+				return err
+			} else if ip := net.ParseIP(host); ip == nil || !isUrlUploadAddressAllowed(ip, allowedNetworks) {
+				return errUrlUploadAddressBlocked
+			}
+
+			return nil
+		},
+	}
+
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: timeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxUrlUploadRedirectCount {
+				return errors.New("重定向次数过多")
+			}
+
+			return checkUploadUrl(req.URL)
+		},
+	}
+
+	return &urlFetcher{client: client, maxSize: maxSize}
+}
+
+func (f *urlFetcher) fetch(rawUrl string) (*urlFetchResult, error) {
+	u, err := url.Parse(rawUrl)
+
+	if err != nil {
+		return nil, winter.NewBadRequestBusinessError("源文件地址不合法")
+	} else if err := checkUploadUrl(u); err != nil {
+		return nil, winter.NewBadRequestBusinessError(err.Error())
+	}
+
+	resp, err := f.client.Get(u.String())
+
+	if errors.Is(err, errUrlUploadAddressBlocked) {
+		return nil, winter.NewBadRequestBusinessError(errUrlUploadAddressBlocked.Error())
+	} else if err != nil {
+		return nil, winter.NewBadRequestBusinessError(fmt.Sprintf("下载远程文件失败：%v", err))
+	} else if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+
+		return nil, winter.NewBadRequestBusinessError(fmt.Sprintf("下载远程文件失败（%d）", resp.StatusCode))
+	} else if resp.ContentLength > f.maxSize {
+		resp.Body.Close()
+
+		return nil, winter.NewBadRequestBusinessError(fmt.Sprintf("文件大小不能超过%d", f.maxSize))
+	}
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	filename := path.Base(resp.Request.URL.Path)
+
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		filename = path.Base(params["filename"])
+	}
+
+	if filename == "/" || filename == "." {
+		filename = ""
+	}
+
+	return &urlFetchResult{
+		Body:          &sizeLimitReadCloser{ReadCloser: resp.Body, maxSize: f.maxSize},
+		ContentType:   contentType,
+		ContentLength: resp.ContentLength,
+		Filename:      filename,
+	}, nil
+}
+
+func checkUploadUrl(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("源文件地址仅支持http和https")
+	} else if u.Hostname() == "" {
+		return errors.New("源文件地址不合法")
+	}
+
+	return nil
+}
+
+func isUrlUploadAddressAllowed(ip net.IP, allowedNetworks []*net.IPNet) bool {
+	for _, ipNet := range allowedNetworks {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	for _, ipNet := range urlUploadBlockedNetworks {
+		if ipNet.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parseCIDRs(cidrs []string) []*net.IPNet {
+	ipNets := make([]*net.IPNet, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		if _, ipNet, err := net.ParseCIDR(strings.TrimSpace(cidr)); err != nil {
+			log.Logger.Warn("解析网段失败", zap.String("cidr", cidr), zap.Error(err))
+		} else {
+			ipNets = append(ipNets, ipNet)
+		}
+	}
+
+	return ipNets
+}
+
+// 读取过程中校验文件大小，超出上限或读完后不足下限时返回错误，避免写入不合规的对象
+type sizeLimitReadCloser struct {
+	io.ReadCloser
+	minSize int64
+	maxSize int64
+	size    int64
+}
+
+func (r *sizeLimitReadCloser) Read(p []byte) (int, error) {
+	if r.size > r.maxSize {
+		return 0, winter.NewBadRequestBusinessError(fmt.Sprintf("文件大小不能超过%d", r.maxSize))
+	} else if int64(len(p)) > r.maxSize-r.size+1 {
+		p = p[:r.maxSize-r.size+1]
+	}
+
+	n, err := r.ReadCloser.Read(p)
+	r.size += int64(n)
+
+	if r.size > r.maxSize {
+		return n, winter.NewBadRequestBusinessError(fmt.Sprintf("文件大小不能超过%d", r.maxSize))
+	} else if errors.Is(err, io.EOF) && r.size < r.minSize {
+		return n, winter.NewBadRequestBusinessError(fmt.Sprintf("文件大小不能小于%d", r.minSize))
+	}
+
+	return n, err
+}
@@ -0,0 +1,102 @@
+package object
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_urlFetcher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/avatar":
+			w.Header().Set("Content-Type", "image/png; charset=binary")
+			w.Header().Set("Content-Disposition", `attachment; filename="a.png"`)
+			w.Write([]byte("png data"))
+		case "/large":
+			w.Write([]byte(strings.Repeat("a", 100)))
+		case "/chunked":
+			w.(http.Flusher).Flush()
+			w.Write([]byte(strings.Repeat("a", 100)))
+		case "/redirect":
+			http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	defer server.Close()
+
+	if _, err := newUrlFetcher(1024, time.Second, nil).fetch(server.URL + "/avatar"); err == nil || err.Error() != errUrlUploadAddressBlocked.Error() {
+		t.Errorf("expected blocked loopback address, got %v", err)
+	}
+
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	fetcher := newUrlFetcher(50, time.Second, []*net.IPNet{loopback})
+
+	if result, err := fetcher.fetch(server.URL + "/avatar"); err != nil {
+		t.Fatal(err)
+	} else {
+		bytes, _ := io.ReadAll(result.Body)
+
+		result.Body.Close()
+
+		if string(bytes) != "png data" || result.ContentType != "image/png" || result.Filename != "a.png" {
+			t.Errorf("unexpected result: %+v %s", result, bytes)
+		}
+	}
+
+	if _, err := fetcher.fetch(server.URL + "/large"); err == nil {
+		t.Error("expected error for declared content length over limit")
+	}
+
+	if result, err := fetcher.fetch(server.URL + "/chunked"); err != nil {
+		t.Fatal(err)
+	} else if _, err := io.ReadAll(result.Body); err == nil {
+		t.Error("expected error for streamed body over limit")
+	} else {
+		result.Body.Close()
+	}
+
+	for _, rawUrl := range []string{"file:///etc/passwd", "ftp://example.com/a", server.URL + "/redirect", server.URL + "/missing"} {
+		if _, err := fetcher.fetch(rawUrl); err == nil {
+			t.Errorf("expected error for %s", rawUrl)
+		}
+	}
+}
+
+func Test_isUrlUploadAddressAllowed(t *testing.T) {
+	for ip, allowed := range map[string]bool{
+		"8.8.8.8":         true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.20.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.100.100.200": false,
+		"::1":             false,
+		"fe80::1":         false,
+		"::ffff:10.0.0.1": false,
+		"2001:4860::8888": true,
+	} {
+		if isUrlUploadAddressAllowed(net.ParseIP(ip), nil) != allowed {
+			t.Errorf("unexpected result for %s", ip)
+		}
+	}
+
+	if !isUrlUploadAddressAllowed(net.ParseIP("10.1.2.3"), parseCIDRs([]string{"10.1.0.0/16"})) {
+		t.Error("expected allow-listed address to be allowed")
+	}
+}
+
+func Test_sizeLimitReadCloser(t *testing.T) {
+	reader := &sizeLimitReadCloser{ReadCloser: io.NopCloser(strings.NewReader("abc")), minSize: 4, maxSize: 10}
+
+	if _, err := io.ReadAll(reader); err == nil {
+		t.Error("expected error for content under min size")
+	}
+}
@@ -1,15 +1,23 @@
 package repository
 
 type App struct {
-	Id              int64  `xorm:"bigint 'id' autoincr pk notnull comment('主键')" json:"id"`
-	AccessKeyId     string `xorm:"varchar(50) 'access_key_id' notnull default('') comment('访问秘钥ID')" json:"accessKeyId"`
-	AccessKeySecret string `xorm:"varchar(50) 'access_key_secret' notnull default('') comment('访问秘钥')" json:"accessKeySecret"`
-	Endpoint        string `xorm:"varchar(200) 'endpoint' notnull default('') comment('端点')" json:"endpoint"`
-	InnerEndpoint   string `xorm:"varchar(200) 'inner_endpoint' notnull default('') comment('内部端点')" json:"innerEndpoint"`
-	Status          int    `xorm:"int 'status' notnull default(1) comment('状态，0：禁用；1：正常')" json:"status"`
-	DelStatus       int    `xorm:"int 'del_status' notnull default(0) comment('删除状态，0：未删除；1：已删除')" json:"-"`
-	CreateTime      string `xorm:"datetime 'create_time' notnull comment('创建时间')" json:"createTime"`
-	UpdateTime      string `xorm:"datetime 'update_time' notnull comment('更新时间')" json:"updateTime"`
+	Id                       int64  `xorm:"bigint 'id' autoincr pk notnull comment('主键')" json:"id"`
+	Provider                 string `xorm:"varchar(50) 'provider' notnull default('oss') comment('存储提供方，oss：阿里云OSS；local：本地文件系统；s3：S3兼容存储')" json:"provider"`
+	AccessKeyId              string `xorm:"varchar(50) 'access_key_id' notnull default('') comment('访问秘钥ID')" json:"accessKeyId"`
+	AccessKeySecret          string `xorm:"varchar(500) 'access_key_secret' notnull default('') comment('访问秘钥（信封加密）')" json:"accessKeySecret"`
+	SecondaryAccessKeyId     string `xorm:"varchar(50) 'secondary_access_key_id' notnull default('') comment('备用访问秘钥ID')" json:"secondaryAccessKeyId"`
+	SecondaryAccessKeySecret string `xorm:"varchar(500) 'secondary_access_key_secret' notnull default('') comment('备用访问秘钥（信封加密）')" json:"secondaryAccessKeySecret"`
+	Endpoint                 string `xorm:"varchar(200) 'endpoint' notnull default('') comment('端点')" json:"endpoint"`
+	InnerEndpoint            string `xorm:"varchar(200) 'inner_endpoint' notnull default('') comment('内部端点')" json:"innerEndpoint"`
+	Region                   string `xorm:"varchar(50) 'region' notnull default('') comment('区域')" json:"region"`
+	BucketLookup             int    `xorm:"int 'bucket_lookup' notnull default(0) comment('S3寻址方式，0：自动；1：路径；2：虚拟主机')" json:"bucketLookup"`
+	SignVersion              string `xorm:"varchar(10) 'sign_version' notnull default('v1') comment('OSS签名版本，v1：HMAC-SHA1；v4：HMAC-SHA256')" json:"signVersion"`
+	RoleArn                  string `xorm:"varchar(200) 'role_arn' notnull default('') comment('STS角色ARN，为空时不签发临时凭证')" json:"roleArn"`
+	StsEndpoint              string `xorm:"varchar(200) 'sts_endpoint' notnull default('') comment('STS端点')" json:"stsEndpoint"`
+	Status                   int    `xorm:"int 'status' notnull default(1) comment('状态，0：禁用；1：正常；2：只读；3：维护中')" json:"status"`
+	DelStatus                int    `xorm:"int 'del_status' notnull default(0) comment('删除状态，0：未删除；1：已删除')" json:"-"`
+	CreateTime               string `xorm:"datetime 'create_time' notnull comment('创建时间')" json:"createTime"`
+	UpdateTime               string `xorm:"datetime 'update_time' notnull comment('更新时间')" json:"updateTime"`
 }
 
 func (*App) TableComment() string {
@@ -25,6 +25,15 @@ func (r *appRepository) FindByBucketName(engine *xorm.Engine, bucketName string)
 	return entity, err
 }
 
+// 包含已删除的应用，用于访问秘钥加密迁移
+func (r *appRepository) FindAllIncludingDeleted(engine *xorm.Engine) ([]App, error) {
+	entities := make([]App, 0)
+
+	err := engine.Asc("id").Find(&entities)
+
+	return entities, err
+}
+
 func (r *appRepository) FindByIdIn(engine *xorm.Engine, ids []int64) ([]App, error) {
 	entities := make([]App, 0)
 
@@ -0,0 +1,15 @@
+package repository
+
+type AppSecretAccessLog struct {
+	Id         int64  `xorm:"bigint 'id' autoincr pk notnull comment('主键')" json:"id"`
+	AppId      int64  `xorm:"bigint 'app_id' notnull default(0) index comment('应用ID')" json:"appId"`
+	Operator   string `xorm:"varchar(100) 'operator' notnull default('') comment('操作人')" json:"operator"`
+	Reason     string `xorm:"varchar(500) 'reason' notnull default('') comment('查看原因')" json:"reason"`
+	ClientIp   string `xorm:"varchar(50) 'client_ip' notnull default('') comment('客户端IP')" json:"clientIp"`
+	Result     int    `xorm:"int 'result' notnull default(0) comment('结果，0：成功；1：拒绝；2：失败')" json:"result"`
+	CreateTime string `xorm:"datetime 'create_time' notnull comment('创建时间')" json:"createTime"`
+}
+
+func (*AppSecretAccessLog) TableComment() string {
+	return "应用访问秘钥查看记录"
+}
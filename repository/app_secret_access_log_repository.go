@@ -0,0 +1,13 @@
+package repository
+
+import "xorm.io/xorm"
+
+type appSecretAccessLogRepository struct{}
+
+var AppSecretAccessLogRepository = &appSecretAccessLogRepository{}
+
+func (r *appSecretAccessLogRepository) Create(engine *xorm.Engine, entity *AppSecretAccessLog) error {
+	_, err := engine.Insert(entity)
+
+	return err
+}
@@ -25,6 +25,22 @@ func (r *bucketRepository) FindByName(engine *xorm.Engine, name string) (*Bucket
 	return entity, err
 }
 
+func (r *bucketRepository) FindAll(engine *xorm.Engine) ([]Bucket, error) {
+	entities := make([]Bucket, 0)
+
+	err := engine.Where("del_status=0").Asc("id").Find(&entities)
+
+	return entities, err
+}
+
+func (r *bucketRepository) FindByAppId(engine *xorm.Engine, appId int64) ([]Bucket, error) {
+	entities := make([]Bucket, 0)
+
+	err := engine.Where("app_id=? AND del_status=0", appId).Asc("id").Find(&entities)
+
+	return entities, err
+}
+
 func (r *bucketRepository) FindByIdIn(engine *xorm.Engine, ids []int64) ([]Bucket, error) {
 	entities := make([]Bucket, 0)
 
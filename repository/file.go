@@ -8,7 +8,17 @@ type File struct {
 	SourceFileSize int64  `xorm:"bigint 'source_file_size' notnull default(0) comment('原文件大小')" json:"sourceFileSize"`
 	SourceFileType string `xorm:"varchar(50) 'source_file_type' notnull default('') comment('原文件类型')" json:"sourceFileType"`
 	SourceFileAttr string `xorm:"varchar(3000) 'source_file_attr' notnull default('') comment('原文件属性')" json:"sourceFileAttr"`
-	DelStatus      int    `xorm:"int 'del_status' notnull default(0) comment('删除状态，0：未删除；1：已删除')" json:"-"`
+	ContentType    string `xorm:"varchar(200) 'content_type' notnull default('') comment('内容类型')" json:"contentType"`
+	ETag           string `xorm:"varchar(100) 'etag' notnull default('') comment('ETag')" json:"etag"`
+	Sha256         string `xorm:"varchar(64) 'sha256' notnull default('') index comment('SHA-256摘要')" json:"sha256"`
+	Md5            string `xorm:"varchar(32) 'md5' notnull default('') comment('MD5摘要')" json:"md5"`
+	Crc64          string `xorm:"varchar(20) 'crc64' notnull default('') comment('CRC64校验值')" json:"crc64"`
+	ImageWidth     int    `xorm:"int 'image_width' notnull default(0) comment('图片宽度')" json:"imageWidth"`
+	ImageHeight    int    `xorm:"int 'image_height' notnull default(0) comment('图片高度')" json:"imageHeight"`
+	ImageFormat    string `xorm:"varchar(20) 'image_format' notnull default('') comment('图片格式')" json:"imageFormat"`
+	Status         int    `xorm:"int 'status' notnull default(0) index comment('上传状态，0：正常；1：待上传；2：上传失败')" json:"status"`
+	UploadExpireAt int64  `xorm:"bigint 'upload_expire_at' notnull default(0) comment('待上传过期时间戳（秒）')" json:"-"`
+	DelStatus      int    `xorm:"int 'del_status' notnull default(0) comment('删除状态，0：未删除；1：回收站；2：已清除')" json:"-"`
 	CreateTime     string `xorm:"datetime 'create_time' notnull comment('创建时间')" json:"createTime"`
 	UpdateTime     string `xorm:"datetime 'update_time' notnull comment('更新时间')" json:"updateTime"`
 }
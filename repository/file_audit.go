@@ -0,0 +1,19 @@
+package repository
+
+type FileAudit struct {
+	Id           int64  `xorm:"bigint 'id' autoincr pk notnull comment('主键')" json:"id"`
+	BucketId     int64  `xorm:"bigint 'bucket_id' notnull default(0) comment('空间ID，0：全部空间')" json:"bucketId"`
+	TriggerType  int    `xorm:"int 'trigger_type' notnull default(0) comment('触发方式，0：定时；1：手动')" json:"triggerType"`
+	Status       int    `xorm:"int 'status' notnull default(0) index comment('状态，0：执行中；1：已完成；2：失败')" json:"status"`
+	CheckedCount int64  `xorm:"bigint 'checked_count' notnull default(0) comment('已检查文件数')" json:"checkedCount"`
+	IssueCount   int64  `xorm:"bigint 'issue_count' notnull default(0) comment('异常文件数')" json:"issueCount"`
+	Message      string `xorm:"varchar(1000) 'message' notnull default('') comment('执行信息')" json:"message"`
+	StartTime    string `xorm:"datetime 'start_time' notnull comment('开始时间')" json:"startTime"`
+	EndTime      string `xorm:"datetime 'end_time' notnull comment('结束时间，执行中时为开始时间')" json:"endTime"`
+	CreateTime   string `xorm:"datetime 'create_time' notnull comment('创建时间')" json:"createTime"`
+	UpdateTime   string `xorm:"datetime 'update_time' notnull comment('更新时间')" json:"updateTime"`
+}
+
+func (*FileAudit) TableComment() string {
+	return "文件巡检"
+}
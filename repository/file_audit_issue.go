@@ -0,0 +1,17 @@
+package repository
+
+type FileAuditIssue struct {
+	Id            int64  `xorm:"bigint 'id' autoincr pk notnull comment('主键')" json:"id"`
+	AuditId       int64  `xorm:"bigint 'audit_id' notnull default(0) index comment('巡检ID')" json:"auditId"`
+	BucketId      int64  `xorm:"bigint 'bucket_id' notnull default(0) comment('空间ID')" json:"bucketId"`
+	FileId        int64  `xorm:"bigint 'file_id' notnull default(0) comment('文件ID')" json:"fileId"`
+	FileKey       string `xorm:"varchar(500) 'file_key' notnull default('') comment('文件键值')" json:"fileKey"`
+	IssueType     int    `xorm:"int 'issue_type' notnull default(0) comment('异常类型，1：对象不存在；2：大小不一致；3：校验值不一致；4：查询失败')" json:"issueType"`
+	ExpectedValue string `xorm:"varchar(200) 'expected_value' notnull default('') comment('期望值')" json:"expectedValue"`
+	ActualValue   string `xorm:"varchar(1000) 'actual_value' notnull default('') comment('实际值')" json:"actualValue"`
+	CreateTime    string `xorm:"datetime 'create_time' notnull comment('创建时间')" json:"createTime"`
+}
+
+func (*FileAuditIssue) TableComment() string {
+	return "文件巡检异常"
+}
@@ -0,0 +1,21 @@
+package repository
+
+import "xorm.io/xorm"
+
+type fileAuditIssueRepository struct{}
+
+var FileAuditIssueRepository = &fileAuditIssueRepository{}
+
+func (r *fileAuditIssueRepository) FindByAuditIdAndIdAfter(engine *xorm.Engine, auditId int64, id int64, limit int) ([]FileAuditIssue, error) {
+	entities := make([]FileAuditIssue, 0)
+
+	err := engine.Where("audit_id=? AND id>?", auditId, id).Asc("id").Limit(limit).Find(&entities)
+
+	return entities, err
+}
+
+func (r *fileAuditIssueRepository) CreateBatch(engine *xorm.Engine, entities []FileAuditIssue) error {
+	_, err := engine.Insert(&entities)
+
+	return err
+}
@@ -0,0 +1,12 @@
+package repository
+
+type FileAuditLock struct {
+	Id           int64  `xorm:"bigint 'id' pk notnull comment('主键，固定为1')" json:"id"`
+	AuditId      int64  `xorm:"bigint 'audit_id' notnull default(0) comment('执行中的巡检ID，0：无')" json:"auditId"`
+	ScheduleTime string `xorm:"datetime 'schedule_time' notnull comment('最近一次定时巡检开始时间')" json:"scheduleTime"`
+	UpdateTime   string `xorm:"datetime 'update_time' notnull comment('更新时间，执行中时为巡检心跳时间')" json:"updateTime"`
+}
+
+func (*FileAuditLock) TableComment() string {
+	return "文件巡检锁"
+}
@@ -0,0 +1,30 @@
+package repository
+
+import "xorm.io/xorm"
+
+type fileAuditLockRepository struct{}
+
+var FileAuditLockRepository = &fileAuditLockRepository{}
+
+func (r *fileAuditLockRepository) FindById(engine *xorm.Engine, id int64) (*FileAuditLock, error) {
+	entity := &FileAuditLock{}
+
+	_, err := engine.ID(id).Get(entity)
+
+	return entity, err
+}
+
+func (r *fileAuditLockRepository) Create(engine *xorm.Engine, entity *FileAuditLock) error {
+	_, err := engine.Insert(entity)
+
+	return err
+}
+
+// 锁持有者和更新时间与读取时一致时才更新，用于多个实例竞争执行巡检
+func (r *fileAuditLockRepository) UpdateByAuditIdAndUpdateTime(engine *xorm.Engine, cols []string, entity *FileAuditLock, auditId int64, updateTime string) (int64, error) {
+	return engine.ID(entity.Id).Where("audit_id=? AND update_time=?", auditId, updateTime).Cols(cols...).Update(entity)
+}
+
+func (r *fileAuditLockRepository) UpdateByAuditId(engine *xorm.Engine, cols []string, entity *FileAuditLock, auditId int64) (int64, error) {
+	return engine.ID(entity.Id).Where("audit_id=?", auditId).Cols(cols...).Update(entity)
+}
@@ -0,0 +1,31 @@
+package repository
+
+import "xorm.io/xorm"
+
+type fileAuditRepository struct{}
+
+var FileAuditRepository = &fileAuditRepository{}
+
+func (r *fileAuditRepository) FindById(engine *xorm.Engine, id int64) (*FileAudit, error) {
+	entity := &FileAudit{}
+
+	_, err := engine.ID(id).Get(entity)
+
+	return entity, err
+}
+
+func (r *fileAuditRepository) Create(engine *xorm.Engine, entity *FileAudit) error {
+	_, err := engine.Insert(entity)
+
+	return err
+}
+
+func (r *fileAuditRepository) Update(engine *xorm.Engine, cols []string, entity *FileAudit) error {
+	_, err := engine.ID(entity.Id).Cols(cols...).Update(entity)
+
+	return err
+}
+
+func (r *fileAuditRepository) DeleteById(engine *xorm.Engine, id int64) (int64, error) {
+	return engine.ID(id).Delete(&FileAudit{})
+}
@@ -0,0 +1,14 @@
+package repository
+
+type FileObject struct {
+	Id         int64  `xorm:"bigint 'id' autoincr pk notnull comment('主键')" json:"id"`
+	BucketId   int64  `xorm:"bigint 'bucket_id' notnull default(0) unique(bucket_file_key) comment('空间ID')" json:"bucketId"`
+	FileKey    string `xorm:"varchar(500) 'file_key' notnull default('') unique(bucket_file_key) comment('文件键值')" json:"fileKey"`
+	RefCount   int64  `xorm:"bigint 'ref_count' notnull default(0) comment('引用数，0：对象已删除或正在删除')" json:"refCount"`
+	CreateTime string `xorm:"datetime 'create_time' notnull comment('创建时间')" json:"createTime"`
+	UpdateTime string `xorm:"datetime 'update_time' notnull comment('更新时间')" json:"updateTime"`
+}
+
+func (*FileObject) TableComment() string {
+	return "去重文件对象引用"
+}
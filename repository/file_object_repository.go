@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"github.com/dromara/carbon/v2"
+	"xorm.io/xorm"
+)
+
+type fileObjectRepository struct{}
+
+var FileObjectRepository = &fileObjectRepository{}
+
+func (r *fileObjectRepository) FindByBucketIdAndFileKey(engine *xorm.Engine, bucketId int64, fileKey string) (*FileObject, error) {
+	entity := &FileObject{}
+
+	_, err := engine.Where("bucket_id=? AND file_key=?", bucketId, fileKey).Get(entity)
+
+	return entity, err
+}
+
+func (r *fileObjectRepository) Create(engine *xorm.Engine, entity *FileObject) error {
+	_, err := engine.Insert(entity)
+
+	return err
+}
+
+// 引用数为0表示对象已删除或正在删除，不再增加引用
+func (r *fileObjectRepository) IncreaseRefCount(engine *xorm.Engine, bucketId int64, fileKey string) (int64, error) {
+	return engine.Where("bucket_id=? AND file_key=? AND ref_count>0", bucketId, fileKey).Incr("ref_count").Update(&FileObject{UpdateTime: carbon.Now().ToDateTimeString()})
+}
+
+func (r *fileObjectRepository) DecreaseRefCount(engine *xorm.Engine, bucketId int64, fileKey string) (int64, error) {
+	return engine.Where("bucket_id=? AND file_key=? AND ref_count>0", bucketId, fileKey).Decr("ref_count").Update(&FileObject{UpdateTime: carbon.Now().ToDateTimeString()})
+}
@@ -1,13 +1,124 @@
 package repository
 
-import "xorm.io/xorm"
+import (
+	"github.com/dromara/carbon/v2"
+	"xorm.io/xorm"
+)
 
 type fileRepository struct{}
 
 var FileRepository = &fileRepository{}
 
+func (r *fileRepository) FindById(engine *xorm.Engine, id int64) (*File, error) {
+	entity := &File{}
+
+	_, err := engine.ID(id).Where("del_status=0").Get(entity)
+
+	return entity, err
+}
+
+func (r *fileRepository) FindDeletedById(engine *xorm.Engine, id int64) (*File, error) {
+	entity := &File{}
+
+	_, err := engine.ID(id).Where("del_status=1").Get(entity)
+
+	return entity, err
+}
+
+func (r *fileRepository) FindDeletedByBucketIdAndUpdateTimeBefore(engine *xorm.Engine, bucketId int64, updateTime string, limit int) ([]File, error) {
+	entities := make([]File, 0)
+
+	err := engine.Where("bucket_id=? AND del_status=1 AND update_time<?", bucketId, updateTime).Asc("id").Limit(limit).Find(&entities)
+
+	return entities, err
+}
+
+// 包含已删除的存储空间
+func (r *fileRepository) FindDeletedBucketIds(engine *xorm.Engine) ([]int64, error) {
+	bucketIds := make([]int64, 0)
+
+	err := engine.Table(&File{}).Where("del_status=1").Distinct("bucket_id").Asc("bucket_id").Find(&bucketIds)
+
+	return bucketIds, err
+}
+
+func (r *fileRepository) FindPendingByUploadExpireAtBefore(engine *xorm.Engine, uploadExpireAt int64, limit int) ([]File, error) {
+	entities := make([]File, 0)
+
+	err := engine.Where("status=1 AND del_status=0 AND upload_expire_at<?", uploadExpireAt).Asc("id").Limit(limit).Find(&entities)
+
+	return entities, err
+}
+
+func (r *fileRepository) FindActiveByBucketIdAndIdAfter(engine *xorm.Engine, bucketId int64, id int64, limit int) ([]File, error) {
+	entities := make([]File, 0)
+
+	err := engine.Where("bucket_id=? AND id>? AND status=0 AND del_status=0", bucketId, id).Asc("id").Limit(limit).Find(&entities)
+
+	return entities, err
+}
+
+func (r *fileRepository) FindByIdIn(engine *xorm.Engine, ids []int64) ([]File, error) {
+	entities := make([]File, 0)
+
+	err := engine.In("id", ids).Where("del_status=0").Find(&entities)
+
+	return entities, err
+}
+
+func (r *fileRepository) FindByBucketIdAndFileKeyIn(engine *xorm.Engine, bucketId int64, fileKeys []string) ([]File, error) {
+	entities := make([]File, 0)
+
+	err := engine.Where("bucket_id=? AND del_status=0", bucketId).In("file_key", fileKeys).Find(&entities)
+
+	return entities, err
+}
+
+func (r *fileRepository) FindActiveByBucketIdAndSha256(engine *xorm.Engine, bucketId int64, sha256 string, size int64) (*File, error) {
+	entity := &File{}
+
+	_, err := engine.Where("bucket_id=? AND sha256=? AND source_file_size=? AND status=0 AND del_status=0", bucketId, sha256, size).Asc("id").Get(entity)
+
+	return entity, err
+}
+
+func (r *fileRepository) FindByBucketIdAndFileKeyAndCreateTimeFrom(engine *xorm.Engine, bucketId int64, fileKey string, createTime string) (*File, error) {
+	entity := &File{}
+
+	_, err := engine.Where("bucket_id=? AND file_key=? AND create_time>=? AND del_status=0", bucketId, fileKey, createTime).Desc("id").Get(entity)
+
+	return entity, err
+}
+
+func (r *fileRepository) CountOthersByBucketIdAndFileKey(engine *xorm.Engine, id int64, bucketId int64, fileKey string) (int64, error) {
+	return engine.Where("id<>? AND bucket_id=? AND file_key=? AND del_status<2", id, bucketId, fileKey).Count(&File{})
+}
+
 func (r *fileRepository) Create(engine *xorm.Engine, entity *File) error {
 	_, err := engine.Insert(entity)
 
 	return err
 }
+
+func (r *fileRepository) Update(engine *xorm.Engine, cols []string, entity *File) error {
+	_, err := engine.ID(entity.Id).Cols(cols...).Update(entity)
+
+	return err
+}
+
+func (r *fileRepository) UpdateByStatus(engine *xorm.Engine, cols []string, entity *File, status int) (int64, error) {
+	return engine.ID(entity.Id).Where("status=?", status).Cols(cols...).Update(entity)
+}
+
+func (r *fileRepository) DeleteById(engine *xorm.Engine, id int64) (int64, error) {
+	return engine.ID(id).Where("del_status=0").Update(&File{DelStatus: 1, UpdateTime: carbon.Now().ToDateTimeString()})
+}
+
+func (r *fileRepository) RestoreById(engine *xorm.Engine, id int64) (int64, error) {
+	return engine.ID(id).Where("del_status=1").Cols("del_status", "update_time").Update(&File{DelStatus: 0, UpdateTime: carbon.Now().ToDateTimeString()})
+}
+
+// 只清除删除状态未变化的文件，避免清除期间已被恢复的文件被误清除
+func (r *fileRepository) PurgeById(engine *xorm.Engine, id int64, delStatus int) (int64, error) {
+	return engine.ID(id).Where("del_status=?", delStatus).Update(&File{DelStatus: 2, UpdateTime: carbon.Now().ToDateTimeString()})
+}
@@ -0,0 +1,25 @@
+package repository
+
+type UploadSession struct {
+	Id              int64  `xorm:"bigint 'id' autoincr pk notnull comment('主键')" json:"id"`
+	UploadId        string `xorm:"varchar(50) 'upload_id' notnull default('') unique comment('上传ID')" json:"uploadId"`
+	BucketId        int64  `xorm:"bigint 'bucket_id' notnull default(0) comment('空间ID')" json:"bucketId"`
+	FileKey         string `xorm:"varchar(500) 'file_key' notnull default('') comment('文件键值')" json:"fileKey"`
+	StorageUploadId string `xorm:"varchar(200) 'storage_upload_id' notnull default('') comment('存储分片上传ID')" json:"-"`
+	SourceFile      string `xorm:"varchar(1000) 'source_file' notnull default('') comment('原文件')" json:"sourceFile"`
+	SourceFileSize  int64  `xorm:"bigint 'source_file_size' notnull default(0) comment('原文件大小')" json:"sourceFileSize"`
+	SourceFileType  string `xorm:"varchar(50) 'source_file_type' notnull default('') comment('原文件类型')" json:"sourceFileType"`
+	SourceFileAttr  string `xorm:"varchar(3000) 'source_file_attr' notnull default('') comment('原文件属性')" json:"sourceFileAttr"`
+	PartSize        int64  `xorm:"bigint 'part_size' notnull default(0) comment('分片大小')" json:"partSize"`
+	UploadType      int    `xorm:"int 'upload_type' notnull default(0) comment('上传方式，0：分片上传；1：tus协议')" json:"uploadType"`
+	UploadOffset    int64  `xorm:"bigint 'upload_offset' notnull default(0) comment('已上传偏移量')" json:"uploadOffset"`
+	FileId          int64  `xorm:"bigint 'file_id' notnull default(0) comment('文件ID')" json:"fileId"`
+	Status          int    `xorm:"int 'status' notnull default(0) comment('状态，0：上传中；1：已完成；2：已取消；3：完成中')" json:"status"`
+	ExpireTime      string `xorm:"datetime 'expire_time' notnull index comment('过期时间')" json:"expireTime"`
+	CreateTime      string `xorm:"datetime 'create_time' notnull comment('创建时间')" json:"createTime"`
+	UpdateTime      string `xorm:"datetime 'update_time' notnull comment('更新时间')" json:"updateTime"`
+}
+
+func (*UploadSession) TableComment() string {
+	return "上传会话"
+}
@@ -0,0 +1,44 @@
+package repository
+
+import "xorm.io/xorm"
+
+type uploadSessionRepository struct{}
+
+var UploadSessionRepository = &uploadSessionRepository{}
+
+func (r *uploadSessionRepository) FindByUploadId(engine *xorm.Engine, uploadId string) (*UploadSession, error) {
+	entity := &UploadSession{}
+
+	_, err := engine.Where("upload_id=?", uploadId).Get(entity)
+
+	return entity, err
+}
+
+// 查询过期的上传中会话和超时未结束的完成中会话
+func (r *uploadSessionRepository) FindExpired(engine *xorm.Engine, expireTime string, completingUpdateTime string, limit int) ([]UploadSession, error) {
+	entities := make([]UploadSession, 0)
+
+	err := engine.Where("(status=0 AND expire_time<?) OR (status=3 AND update_time<?)", expireTime, completingUpdateTime).Asc("id").Limit(limit).Find(&entities)
+
+	return entities, err
+}
+
+func (r *uploadSessionRepository) Create(engine *xorm.Engine, entity *UploadSession) error {
+	_, err := engine.Insert(entity)
+
+	return err
+}
+
+func (r *uploadSessionRepository) Update(engine *xorm.Engine, cols []string, entity *UploadSession) error {
+	_, err := engine.ID(entity.Id).Cols(cols...).Update(entity)
+
+	return err
+}
+
+func (r *uploadSessionRepository) UpdateUploadOffset(engine *xorm.Engine, entity *UploadSession, uploadOffset int64) (int64, error) {
+	return engine.ID(entity.Id).Where("status=0 AND upload_offset=?", uploadOffset).Cols("upload_offset", "update_time").Update(entity)
+}
+
+func (r *uploadSessionRepository) UpdateStatus(engine *xorm.Engine, entity *UploadSession, status int, updateTime string) (int64, error) {
+	return engine.ID(entity.Id).Where("status=? AND update_time=?", status, updateTime).Cols("status", "file_id", "update_time").Update(entity)
+}
@@ -0,0 +1,24 @@
+package router
+
+import (
+	"github.com/easynet-cn/file-service/log"
+	"github.com/easynet-cn/file-service/object"
+	"go.uber.org/zap"
+)
+
+// RunMigrateAppSecrets 加密存量应用访问秘钥后退出，不启动服务
+func RunMigrateAppSecrets() {
+	log.Logger = GinApplication.GetLogger()
+	object.Config = GinApplication.GetConfig()
+	object.Database = GinApplication.GetDatabase()
+
+	if err := object.SyncDB(); err != nil {
+		log.Logger.Fatal("同步数据库失败", zap.Error(err))
+	} else if result, err := object.MigrateAppSecrets(); err != nil {
+		log.Logger.Fatal("加密应用访问秘钥失败", zap.Error(err))
+	} else if result.Failed > 0 {
+		log.Logger.Fatal("部分应用访问秘钥加密失败", zap.Any("result", result))
+	} else {
+		log.Logger.Info("应用访问秘钥加密完成", zap.Any("result", result))
+	}
+}
@@ -15,11 +15,15 @@ var (
 
 func RunApplication() {
 	log.Logger = GinApplication.GetLogger()
+	object.Config = GinApplication.GetConfig()
 	object.Nacos = GinApplication.GetNacos()
 	object.Database = GinApplication.GetDatabase()
+	object.Redis = GinApplication.GetRedis()
 
 	GinApplication.Run(
-		InitRouter)
+		InitRouter,
+		InitScheduler,
+		InitSubscriber)
 }
 
 func InitRouter() {
@@ -27,21 +31,61 @@ func InitRouter() {
 
 	apiGroup := server.Group("/v1/")
 
-	apiGroup.POST("/apps/search/page", controller.AppController.SearchPage) //应用分页查询
-	apiGroup.POST("/apps", controller.AppController.Create)                 //创建应用
-	apiGroup.PUT("/apps/:id", controller.AppController.Update)              //更新应用
-	apiGroup.DELETE("/apps/:id", controller.AppController.Delete)           //删除应用
+	apiGroup.POST("/apps/search/page", controller.AppController.SearchPage)                                //应用分页查询
+	apiGroup.POST("/apps", controller.AppController.Create)                                                //创建应用
+	apiGroup.PUT("/apps/:id", controller.AppController.Update)                                             //更新应用
+	apiGroup.DELETE("/apps/:id", controller.AppController.Delete)                                          //删除应用
+	apiGroup.POST("/apps/:id/secret/reveal", controller.AppController.RevealSecret)                        //查看应用访问秘钥
+	apiGroup.PUT("/apps/:id/credentials/secondary", controller.AppController.SetSecondaryCredential)       //设置应用备用访问秘钥
+	apiGroup.POST("/apps/:id/credentials/cutover", controller.AppController.CutoverCredential)             //切换应用访问秘钥
+	apiGroup.DELETE("/apps/:id/credentials/secondary", controller.AppController.DeleteSecondaryCredential) //删除应用备用访问秘钥
+	apiGroup.POST("/apps/:id/test", controller.AppController.Test)                                         //应用连通性自检
 
 	apiGroup.POST("/buckets/search/page", controller.BucketController.SearchPage) //存储空间分页查询
 	apiGroup.POST("/buckets", controller.BucketController.Create)                 //创建存储空间
 	apiGroup.PUT("/buckets/:id", controller.BucketController.Update)              //更新存储空间
 	apiGroup.DELETE("/buckets/:id", controller.BucketController.Delete)           //删除存储空间
+	apiGroup.POST("/buckets/:id/test", controller.BucketController.Test)          //存储空间连通性自检
 
-	apiGroup.POST("/files/search", controller.FileController.Search)               //文件查询
-	apiGroup.POST("/files/search/page", controller.FileController.SearchPage)      //文件分页查询
-	apiGroup.POST("/files/upload/token", controller.FileController.GetUploadToken) //获取上传凭证
-	apiGroup.POST("/files/upload", controller.FileController.Upload)               //上传文件
-	apiGroup.POST("/files/upload/base64", controller.FileController.UploadBase64)  //上传Base64文件
-	apiGroup.POST("/files", controller.FileController.Create)                      //创建文件数据
-	apiGroup.POST("/files/batch", controller.FileController.CreateBatch)           //批量创建文件数据
+	apiGroup.POST("/files/search", controller.FileController.Search)                           //文件查询
+	apiGroup.POST("/files/search/page", controller.FileController.SearchPage)                  //文件分页查询
+	apiGroup.POST("/files/upload/token", controller.FileController.GetUploadToken)             //获取上传凭证
+	apiGroup.POST("/files/upload/credentials", controller.FileController.GetUploadCredentials) //获取临时上传凭证
+	apiGroup.POST("/files/upload/instant", controller.FileController.InstantUpload)            //秒传检查
+	apiGroup.POST("/files/upload", controller.FileController.Upload)                           //上传文件
+	apiGroup.POST("/files/upload/base64", controller.FileController.UploadBase64)              //上传Base64文件
+	apiGroup.POST("/files/upload/url", controller.FileController.UploadFromUrl)                //从URL上传文件
+	apiGroup.POST("/files", controller.FileController.Create)                                  //创建文件数据
+	apiGroup.POST("/files/batch", controller.FileController.CreateBatch)                       //批量创建文件数据
+	apiGroup.DELETE("/files/:id", controller.FileController.Delete)                            //删除文件
+	apiGroup.POST("/files/delete/batch", controller.FileController.DeleteBatch)                //批量删除文件
+	apiGroup.POST("/files/trash/search/page", controller.FileController.TrashSearchPage)       //回收站文件分页查询
+	apiGroup.POST("/files/:id/restore", controller.FileController.Restore)                     //从回收站恢复文件
+	apiGroup.POST("/files/:id/confirm", controller.FileController.Confirm)                     //确认文件已上传
+	apiGroup.POST("/files/callback/oss", controller.FileController.OssCallback)                //OSS上传回调
+
+	apiGroup.POST("/files/audits", controller.FileAuditController.Start)                                  //发起文件巡检
+	apiGroup.POST("/files/audits/search/page", controller.FileAuditController.SearchPage)                 //文件巡检分页查询
+	apiGroup.GET("/files/audits/:id", controller.FileAuditController.Get)                                 //查询文件巡检
+	apiGroup.POST("/files/audits/:id/issues/search/page", controller.FileAuditController.SearchIssuePage) //巡检异常分页查询
+	apiGroup.GET("/files/audits/:id/issues/export", controller.FileAuditController.ExportIssues)          //导出巡检异常
+
+	apiGroup.POST("/files/uploads", controller.UploadSessionController.Initiate)                              //创建分片上传会话
+	apiGroup.POST("/files/uploads/presigned", controller.UploadSessionController.InitiatePresigned)           //创建直传分片上传会话
+	apiGroup.POST("/files/uploads/:uploadId/presign", controller.UploadSessionController.PresignParts)        //获取分片直传地址
+	apiGroup.PUT("/files/uploads/:uploadId/parts/:partNumber", controller.UploadSessionController.UploadPart) //上传分片
+	apiGroup.GET("/files/uploads/:uploadId/parts", controller.UploadSessionController.ListParts)              //查询已上传分片
+	apiGroup.POST("/files/uploads/:uploadId/complete", controller.UploadSessionController.Complete)           //完成分片上传
+	apiGroup.DELETE("/files/uploads/:uploadId", controller.UploadSessionController.Abort)                     //取消分片上传
+
+	apiGroup.OPTIONS("/files/tus/", controller.TusController.Options)        //tus协议能力查询
+	apiGroup.POST("/files/tus/", controller.TusController.Create)            //tus创建上传
+	apiGroup.HEAD("/files/tus/:uploadId", controller.TusController.Head)     //tus查询上传偏移量
+	apiGroup.PATCH("/files/tus/:uploadId", controller.TusController.Patch)   //tus追加上传数据
+	apiGroup.DELETE("/files/tus/:uploadId", controller.TusController.Delete) //tus终止上传
+	apiGroup.POST("/files/tus/:uploadId", controller.TusController.Override) //tus方法覆盖
+
+	apiGroup.GET("/files/local/:bucket/*key", controller.FileController.LocalDownload) //本地存储文件下载
+	apiGroup.POST("/files/local/:bucket", controller.FileController.LocalUpload)       //本地存储表单上传
+	apiGroup.PUT("/files/local/:bucket/*key", controller.FileController.LocalPut)      //本地存储PUT直传
 }
@@ -0,0 +1,42 @@
+package router
+
+import (
+	"time"
+
+	"github.com/easynet-cn/file-service/log"
+	"github.com/easynet-cn/file-service/object"
+	"github.com/easynet-cn/winter"
+	"github.com/go-co-op/gocron/v2"
+	"go.uber.org/zap"
+)
+
+func InitScheduler() {
+	innerScheduler, err := gocron.NewScheduler()
+
+	if err != nil {
+		panic(err)
+	}
+
+	jobs := []struct {
+		name       string
+		definition gocron.JobDefinition
+		function   any
+	}{
+		{"purgeTrashFiles", gocron.DurationJob(time.Hour), object.PurgeTrashFiles},                       //清除回收站过期文件
+		{"cleanExpiredUploadSessions", gocron.DurationJob(time.Hour), object.CleanExpiredUploadSessions}, //清理过期上传会话
+		{"expirePendingFiles", gocron.DurationJob(10 * time.Minute), object.ExpirePendingFiles},          //处理过期待上传文件
+		{"auditFiles", gocron.DurationJob(24 * time.Hour), object.RunFileAudit},                          //文件巡检
+	}
+
+	for _, job := range jobs {
+		if _, err := innerScheduler.NewJob(job.definition, gocron.NewTask(job.function), gocron.WithName(job.name), gocron.WithTags(job.name), gocron.WithSingletonMode(gocron.LimitModeReschedule)); err != nil {
+			log.Logger.Error("创建定时任务失败", zap.String("job", job.name), zap.Error(err))
+		}
+	}
+
+	scheduler := winter.NewScheduler(innerScheduler, "file-service", "文件服务调度器")
+
+	GinApplication.RegisterScheduler(scheduler)
+
+	scheduler.Start()
+}
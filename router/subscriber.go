@@ -0,0 +1,11 @@
+package router
+
+import (
+	"context"
+
+	"github.com/easynet-cn/file-service/object"
+)
+
+func InitSubscriber() {
+	object.SubscribeAppChanged(context.Background()) //应用变更通知
+}
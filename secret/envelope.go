@@ -0,0 +1,119 @@
+package secret
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+const (
+	envelopePrefix = "enc:v1:"
+	dataKeySize    = 32
+)
+
+var (
+	ErrInvalidCiphertext = errors.New("密文不合法")
+	ErrNoKeyProvider     = errors.New("未配置主密钥")
+)
+
+// Encrypt 信封加密：使用随机数据密钥加密明文，数据密钥由主密钥加密后与密文一起保存
+//
+// 密文格式：enc:v1:<主密钥版本>:<加密的数据密钥>:<加密的明文>
+func Encrypt(provider KeyProvider, plaintext string) (string, error) {
+	if provider == nil {
+		return "", ErrNoKeyProvider
+	}
+
+	dataKey := make([]byte, dataKeySize)
+
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+
+	aead, err := newAEAD(dataKey)
+
+	if err != nil {
+		return "", err
+	}
+
+	version, encryptedKey, err := provider.EncryptKey(dataKey)
+
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := seal(aead, []byte(plaintext), []byte(version))
+
+	if err != nil {
+		return "", err
+	}
+
+	return envelopePrefix + version + ":" + base64.RawURLEncoding.EncodeToString(encryptedKey) + ":" + base64.RawURLEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt 解密信封密文，未加密的值原样返回
+func Decrypt(provider KeyProvider, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	} else if provider == nil {
+		return "", ErrNoKeyProvider
+	}
+
+	version, encryptedKey, ciphertext, err := parseEnvelope(value)
+
+	if err != nil {
+		return "", err
+	}
+
+	dataKey, err := provider.DecryptKey(version, encryptedKey)
+
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newAEAD(dataKey)
+
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := open(aead, ciphertext, []byte(version))
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, envelopePrefix)
+}
+
+// KeyVersion 返回密文使用的主密钥版本，未加密时返回空
+func KeyVersion(value string) string {
+	if version, _, _, err := parseEnvelope(value); err != nil {
+		return ""
+	} else {
+		return version
+	}
+}
+
+func parseEnvelope(value string) (string, []byte, []byte, error) {
+	if !IsEncrypted(value) {
+		return "", nil, nil, ErrInvalidCiphertext
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, envelopePrefix), ":")
+
+	if len(parts) != 3 || parts[0] == "" {
+		return "", nil, nil, ErrInvalidCiphertext
+	} else if encryptedKey, err := base64.RawURLEncoding.DecodeString(parts[1]); err != nil {
+		return "", nil, nil, ErrInvalidCiphertext
+	} else if ciphertext, err := base64.RawURLEncoding.DecodeString(parts[2]); err != nil {
+		return "", nil, nil, ErrInvalidCiphertext
+	} else {
+		return parts[0], encryptedKey, ciphertext, nil
+	}
+}
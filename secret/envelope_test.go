@@ -0,0 +1,101 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	v1 := bytes.Repeat([]byte{1}, masterKeySize)
+	v2 := bytes.Repeat([]byte{2}, masterKeySize)
+
+	oldProvider, err := NewLocalKeyProvider(map[string][]byte{"v1": v1}, "v1")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := Encrypt(oldProvider, "secret")
+
+	if err != nil {
+		t.Fatal(err)
+	} else if !IsEncrypted(value) || strings.Contains(value, "secret") || KeyVersion(value) != "v1" {
+		t.Fatalf("unexpected ciphertext: %s", value)
+	} else if other, _ := Encrypt(oldProvider, "secret"); other == value {
+		t.Error("expected random data key per encryption")
+	}
+
+	newProvider, err := NewLocalKeyProvider(map[string][]byte{"v1": v1, "v2": v2}, "v2")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plaintext, err := Decrypt(newProvider, value); err != nil || plaintext != "secret" {
+		t.Errorf("expected old version readable after rotation, got %q %v", plaintext, err)
+	} else if rotated, err := Encrypt(newProvider, plaintext); err != nil || KeyVersion(rotated) != "v2" {
+		t.Errorf("expected current version v2, got %q %v", rotated, err)
+	} else if _, err := Decrypt(oldProvider, rotated); !errors.Is(err, ErrKeyVersionNotFound) {
+		t.Errorf("expected unknown key version, got %v", err)
+	}
+
+	if plaintext, err := Decrypt(nil, "plain"); err != nil || plaintext != "plain" {
+		t.Errorf("expected plaintext passthrough, got %q %v", plaintext, err)
+	} else if _, err := Decrypt(nil, value); !errors.Is(err, ErrNoKeyProvider) {
+		t.Errorf("expected missing provider, got %v", err)
+	}
+
+	tampered := value[:len(value)-2] + "AA"
+
+	if tampered == value {
+		tampered = value[:len(value)-2] + "BB"
+	}
+
+	if _, err := Decrypt(newProvider, tampered); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("expected invalid ciphertext, got %v", err)
+	} else if _, err := Decrypt(newProvider, strings.Replace(value, "enc:v1:v1:", "enc:v1:v2:", 1)); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("expected key version bound to ciphertext, got %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	v1 := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, masterKeySize))
+	v2 := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, masterKeySize))
+	keyFile := filepath.Join(t.TempDir(), "master-keys")
+
+	if err := os.WriteFile(keyFile, []byte("# master keys\nv1="+v1+"\n\nv2 = "+v2+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, c := range map[string]struct {
+		config  Config
+		version string
+	}{
+		"local":        {Config{MasterKeys: map[string]string{"v1": v1, "v2": v2}, CurrentVersion: "v1"}, "v1"},
+		"file":         {Config{Provider: ProviderFile, KeyFile: keyFile}, "v2"},
+		"file-version": {Config{Provider: ProviderFile, KeyFile: keyFile, CurrentVersion: "v1"}, "v1"},
+	} {
+		if provider, err := New(c.config); err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if provider.CurrentVersion() != c.version {
+			t.Errorf("%s: expected version %s, got %s", name, c.version, provider.CurrentVersion())
+		}
+	}
+
+	for name, config := range map[string]Config{
+		"unknown-provider": {Provider: "kms"},
+		"missing-version":  {MasterKeys: map[string]string{"v1": v1}, CurrentVersion: "v2"},
+		"short-key":        {MasterKeys: map[string]string{"v1": base64.StdEncoding.EncodeToString([]byte("short"))}, CurrentVersion: "v1"},
+		"invalid-version":  {MasterKeys: map[string]string{"v:1": v1}, CurrentVersion: "v:1"},
+		"missing-file":     {Provider: ProviderFile, KeyFile: filepath.Join(t.TempDir(), "missing")},
+	} {
+		if _, err := New(config); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package secret
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	ProviderLocal = "local" //主密钥来自配置
+	ProviderFile  = "file"  //主密钥来自本地密钥文件
+)
+
+const (
+	masterKeySize = 32
+)
+
+var (
+	ErrKeyVersionNotFound = errors.New("主密钥版本不存在")
+)
+
+// KeyProvider 主密钥提供方，只负责数据密钥的加解密，主密钥本身不离开提供方，可替换为KMS实现
+type KeyProvider interface {
+	// CurrentVersion 当前用于加密的主密钥版本
+	CurrentVersion() string
+	// EncryptKey 使用当前主密钥加密数据密钥，返回主密钥版本和密文
+	EncryptKey(dataKey []byte) (string, []byte, error)
+	// DecryptKey 使用指定版本的主密钥解密数据密钥
+	DecryptKey(version string, encryptedKey []byte) ([]byte, error)
+}
+
+type Config struct {
+	Provider       string            //主密钥提供方
+	CurrentVersion string            //当前主密钥版本，为空时使用密钥文件最后一个版本
+	MasterKeys     map[string]string //主密钥（版本 -> Base64编码的32字节密钥）
+	KeyFile        string            //密钥文件路径，每行一个“版本=Base64密钥”
+}
+
+func New(config Config) (KeyProvider, error) {
+	switch config.Provider {
+	case "", ProviderLocal:
+		if keys, err := decodeMasterKeys(config.MasterKeys); err != nil {
+			return nil, err
+		} else {
+			return NewLocalKeyProvider(keys, config.CurrentVersion)
+		}
+	case ProviderFile:
+		return NewFileKeyProvider(config.KeyFile, config.CurrentVersion)
+	default:
+		return nil, fmt.Errorf("不支持的主密钥提供方：%s", config.Provider)
+	}
+}
+
+type LocalKeyProvider struct {
+	keys           map[string]cipher.AEAD
+	currentVersion string
+}
+
+func NewLocalKeyProvider(keys map[string][]byte, currentVersion string) (*LocalKeyProvider, error) {
+	if currentVersion == "" {
+		return nil, errors.New("未指定当前主密钥版本")
+	} else if _, ok := keys[currentVersion]; !ok {
+		return nil, fmt.Errorf("%w：%s", ErrKeyVersionNotFound, currentVersion)
+	}
+
+	p := &LocalKeyProvider{keys: make(map[string]cipher.AEAD, len(keys)), currentVersion: currentVersion}
+
+	for version, key := range keys {
+		if version == "" || strings.ContainsAny(version, ":= \t") {
+			return nil, fmt.Errorf("主密钥版本不合法：%q", version)
+		} else if len(key) != masterKeySize {
+			return nil, fmt.Errorf("主密钥（%s）长度必须为%d字节", version, masterKeySize)
+		} else if aead, err := newAEAD(key); err != nil {
+			return nil, err
+		} else {
+			p.keys[version] = aead
+		}
+	}
+
+	return p, nil
+}
+
+// NewFileKeyProvider 从本地密钥文件加载主密钥，未指定当前版本时使用文件中最后一个版本
+func NewFileKeyProvider(path string, currentVersion string) (*LocalKeyProvider, error) {
+	if path == "" {
+		return nil, errors.New("未指定主密钥文件")
+	}
+
+	content, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string][]byte)
+	lastVersion := ""
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		version, encodedKey, ok := strings.Cut(line, "=")
+
+		if !ok {
+			return nil, fmt.Errorf("主密钥文件格式不正确：%s", path)
+		} else if key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey)); err != nil {
+			return nil, fmt.Errorf("主密钥（%s）不是合法的Base64编码", strings.TrimSpace(version))
+		} else {
+			lastVersion = strings.TrimSpace(version)
+			keys[lastVersion] = key
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if currentVersion == "" {
+		currentVersion = lastVersion
+	}
+
+	return NewLocalKeyProvider(keys, currentVersion)
+}
+
+func (p *LocalKeyProvider) CurrentVersion() string {
+	return p.currentVersion
+}
+
+func (p *LocalKeyProvider) EncryptKey(dataKey []byte) (string, []byte, error) {
+	encryptedKey, err := seal(p.keys[p.currentVersion], dataKey, []byte(p.currentVersion))
+
+	return p.currentVersion, encryptedKey, err
+}
+
+func (p *LocalKeyProvider) DecryptKey(version string, encryptedKey []byte) ([]byte, error) {
+	if aead, ok := p.keys[version]; !ok {
+		return nil, fmt.Errorf("%w：%s", ErrKeyVersionNotFound, version)
+	} else {
+		return open(aead, encryptedKey, []byte(version))
+	}
+}
+
+func decodeMasterKeys(encodedKeys map[string]string) (map[string][]byte, error) {
+	keys := make(map[string][]byte, len(encodedKeys))
+
+	for version, encodedKey := range encodedKeys {
+		if key, err := base64.StdEncoding.DecodeString(encodedKey); err != nil {
+			return nil, fmt.Errorf("主密钥（%s）不是合法的Base64编码", version)
+		} else {
+			keys[version] = key
+		}
+	}
+
+	return keys, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// 加密结果为随机nonce拼接密文
+func seal(aead cipher.AEAD, plaintext []byte, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, ciphertext []byte, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	plaintext, err := aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], additionalData)
+
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	ProviderOss   = "oss"   //阿里云OSS
+	ProviderLocal = "local" //本地文件系统
+	ProviderS3    = "s3"    //S3兼容存储
+)
+
+const (
+	BucketLookupAuto = 0 //自动
+	BucketLookupPath = 1 //路径寻址
+	BucketLookupDNS  = 2 //虚拟主机寻址
+)
+
+const (
+	SignVersionV1 = "v1" //HMAC-SHA1签名
+	SignVersionV4 = "v4" //HMAC-SHA256签名
+)
+
+var (
+	ErrObjectNotFound = errors.New("对象不存在")
+)
+
+type Config struct {
+	Provider        string //存储提供方
+	Endpoint        string //端点
+	InnerEndpoint   string //内部端点
+	AccessKeyId     string //访问秘钥ID
+	AccessKeySecret string //访问秘钥
+	Region          string //区域
+	BucketLookup    int    //寻址方式
+	SignVersion     string //签名版本
+	RoleArn         string //STS角色ARN
+	StsEndpoint     string //STS端点
+}
+
+type ObjectInfo struct {
+	Key          string    `json:"key"`          //对象键值
+	Size         int64     `json:"size"`         //对象大小
+	ContentType  string    `json:"contentType"`  //内容类型
+	ETag         string    `json:"etag"`         //ETag
+	Crc64        string    `json:"crc64"`        //CRC64校验值（ECMA），存储不支持时为空
+	LastModified time.Time `json:"lastModified"` //最后修改时间
+}
+
+type ListResult struct {
+	Objects     []ObjectInfo `json:"objects"`     //对象集合
+	NextMarker  string       `json:"nextMarker"`  //下一页标记
+	IsTruncated bool         `json:"isTruncated"` //是否还有数据
+}
+
+type Part struct {
+	PartNumber int    `json:"partNumber"` //分片号
+	ETag       string `json:"etag"`       //ETag
+	Size       int64  `json:"size"`       //分片大小
+}
+
+type PutOptions struct {
+	ContentType string //内容类型
+	ContentMD5  string //内容MD5（Base64编码）
+}
+
+type SignOptions struct {
+	Method       string            //请求方法
+	ExpiredInSec int64             //过期秒数
+	Process      string            //处理参数
+	Domain       string            //访问域名
+	Params       map[string]string //附加查询参数
+	Headers      map[string]string //参与签名的请求头
+}
+
+type PostPolicyOptions struct {
+	Domain              string            //上传域名
+	ExpiredInSec        int64             //过期秒数
+	Callback            *Callback         //上传回调
+	MinSize             int64             //最小文件大小
+	MaxSize             int64             //最大文件大小，0：不限制
+	ContentType         string            //内容类型
+	ContentTypePrefix   string            //内容类型前缀
+	ContentDisposition  string            //内容描述
+	SuccessActionStatus string            //上传成功返回的状态码
+	Meta                map[string]string //自定义元数据
+}
+
+type Callback struct {
+	Url      string `json:"callbackUrl"`      //回调地址
+	Body     string `json:"callbackBody"`     //回调请求体
+	BodyType string `json:"callbackBodyType"` //回调请求体类型
+}
+
+func (c *Callback) Encode() (string, error) {
+	if bytes, err := json.Marshal(c); err != nil {
+		return "", err
+	} else {
+		return base64.StdEncoding.EncodeToString(bytes), nil
+	}
+}
+
+type PostPolicyToken struct {
+	UploadUrl   string            //上传地址
+	AccessKeyId string            //签名使用的秘钥ID
+	Policy      string            //策略
+	Signature   string            //签名
+	Fields      map[string]string //表单字段
+}
+
+type Backend interface {
+	Put(bucket string, key string, reader io.Reader, options PutOptions) error
+	PutFile(bucket string, key string, file string) error
+	Get(bucket string, key string) (io.ReadCloser, error)
+	GetRange(bucket string, key string, offset int64, length int64) (io.ReadCloser, error)
+	Head(bucket string, key string) (*ObjectInfo, error)
+	Delete(bucket string, key string) error
+	Copy(bucket string, srcKey string, destKey string) error
+	List(bucket string, prefix string, marker string, maxKeys int) (*ListResult, error)
+	SignURL(bucket string, key string, options SignOptions) (string, error)
+	PostPolicyToken(bucket string, key string, options PostPolicyOptions) (*PostPolicyToken, error)
+	InitiateMultipartUpload(bucket string, key string, options PutOptions) (string, error)
+	UploadPart(bucket string, key string, uploadId string, partNumber int, reader io.Reader, size int64) (*Part, error)
+	ListParts(bucket string, key string, uploadId string) ([]Part, error)
+	CompleteMultipartUpload(bucket string, key string, uploadId string, parts []Part) error
+	AbortMultipartUpload(bucket string, key string, uploadId string) error
+}
+
+type Factory func(config Config) (Backend, error)
+
+var (
+	factories = map[string]Factory{
+		ProviderOss:   NewOssBackend,
+		ProviderLocal: NewLocalBackend,
+		ProviderS3:    NewS3Backend,
+	}
+)
+
+func Register(provider string, factory Factory) {
+	factories[provider] = factory
+}
+
+func New(config Config) (Backend, error) {
+	provider := config.Provider
+
+	if provider == "" {
+		provider = ProviderOss
+	}
+
+	if factory, ok := factories[provider]; !ok {
+		return nil, fmt.Errorf("不支持的存储提供方：%s", provider)
+	} else {
+		return factory(config)
+	}
+}
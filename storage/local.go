@@ -0,0 +1,550 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	LocalRoutePrefix        = "/v1/files/local" //本地存储访问路由前缀
+	LocalSignedHeadersParam = "SignedHeaders"   //参与签名的请求头，多个以分号分隔
+)
+
+var (
+	ErrInvalidKey       = errors.New("无效的文件键值")
+	ErrInvalidSignature = errors.New("签名无效")
+	ErrSignatureExpired = errors.New("签名已过期")
+	ErrInvalidUploadId  = errors.New("无效的分片上传ID")
+	ErrPolicyViolation  = errors.New("上传内容不符合策略")
+	ErrBadDigest        = errors.New("内容MD5不匹配")
+)
+
+const (
+	localMultipartDir = ".multipart"
+	localUploadFile   = ".upload"
+)
+
+type SignatureVerifier interface {
+	VerifyURL(method string, bucket string, key string, params url.Values, expires int64, signature string) error
+	VerifyPostPolicy(bucket string, key string, fields map[string]string, size int64, policy string, signature string) error
+}
+
+type localBackend struct {
+	config Config
+	root   string
+}
+
+func NewLocalBackend(config Config) (Backend, error) {
+	if config.Endpoint == "" {
+		return nil, errors.New("本地存储根目录不能为空")
+	} else if config.AccessKeySecret == "" {
+		return nil, errors.New("本地存储访问秘钥不能为空，访问地址和上传策略使用访问秘钥签名")
+	} else if root, err := filepath.Abs(config.Endpoint); err != nil {
+		return nil, err
+	} else {
+		return &localBackend{config: config, root: root}, nil
+	}
+}
+
+func (b *localBackend) Put(bucket string, key string, reader io.Reader, options PutOptions) error {
+	file, err := b.objectPath(bucket, key)
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0750); err != nil {
+		return err
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(file), ".upload-*")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tempFile.Name())
+
+	hash := md5.New()
+
+	if _, err := io.Copy(io.MultiWriter(tempFile, hash), reader); err != nil {
+		tempFile.Close()
+
+		return err
+	}
+
+	if err := tempFile.Close(); err != nil {
+		return err
+	} else if options.ContentMD5 != "" && options.ContentMD5 != base64.StdEncoding.EncodeToString(hash.Sum(nil)) {
+		return ErrBadDigest
+	}
+
+	return os.Rename(tempFile.Name(), file)
+}
+
+func (b *localBackend) PutFile(bucket string, key string, file string) error {
+	if f, err := os.Open(file); err != nil {
+		return err
+	} else {
+		defer f.Close()
+
+		return b.Put(bucket, key, f, PutOptions{})
+	}
+}
+
+func (b *localBackend) Get(bucket string, key string) (io.ReadCloser, error) {
+	if file, err := b.objectPath(bucket, key); err != nil {
+		return nil, err
+	} else {
+		return os.Open(file)
+	}
+}
+
+func (b *localBackend) GetRange(bucket string, key string, offset int64, length int64) (io.ReadCloser, error) {
+	if file, err := b.objectPath(bucket, key); err != nil {
+		return nil, err
+	} else if f, err := os.Open(file); err != nil {
+		return nil, err
+	} else {
+		return struct {
+			io.Reader
+			io.Closer
+		}{io.NewSectionReader(f, offset, length), f}, nil
+	}
+}
+
+func (b *localBackend) Head(bucket string, key string) (*ObjectInfo, error) {
+	if file, err := b.objectPath(bucket, key); err != nil {
+		return nil, err
+	} else if fileInfo, err := os.Stat(file); errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	} else if err != nil {
+		return nil, err
+	} else {
+		return &ObjectInfo{
+			Key:          key,
+			Size:         fileInfo.Size(),
+			ContentType:  mime.TypeByExtension(path.Ext(key)),
+			ETag:         fmt.Sprintf("%x-%x", fileInfo.ModTime().UnixNano(), fileInfo.Size()),
+			LastModified: fileInfo.ModTime(),
+		}, nil
+	}
+}
+
+func (b *localBackend) Delete(bucket string, key string) error {
+	if file, err := b.objectPath(bucket, key); err != nil {
+		return err
+	} else if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (b *localBackend) Copy(bucket string, srcKey string, destKey string) error {
+	if reader, err := b.Get(bucket, srcKey); err != nil {
+		return err
+	} else {
+		defer reader.Close()
+
+		return b.Put(bucket, destKey, reader, PutOptions{})
+	}
+}
+
+func (b *localBackend) List(bucket string, prefix string, marker string, maxKeys int) (*ListResult, error) {
+	bucketRoot := filepath.Join(b.root, bucket)
+	keys := make([]string, 0)
+
+	err := filepath.WalkDir(bucketRoot, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+
+			return err
+		}
+
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+
+		if rel, err := filepath.Rel(bucketRoot, file); err != nil {
+			return err
+		} else if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) && key > marker {
+			keys = append(keys, key)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(keys)
+
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	listResult := &ListResult{Objects: make([]ObjectInfo, 0, min(len(keys), maxKeys))}
+
+	for i, key := range keys {
+		if i >= maxKeys {
+			listResult.IsTruncated = true
+			listResult.NextMarker = keys[i-1]
+
+			break
+		}
+
+		if objectInfo, err := b.Head(bucket, key); err == nil {
+			listResult.Objects = append(listResult.Objects, *objectInfo)
+		}
+	}
+
+	return listResult, nil
+}
+
+func (b *localBackend) SignURL(bucket string, key string, options SignOptions) (string, error) {
+	method := options.Method
+
+	if method == "" {
+		method = "GET"
+	}
+
+	expires := time.Now().Unix() + options.ExpiredInSec
+	params := url.Values{}
+
+	for k, v := range options.Params {
+		params.Set(k, v)
+	}
+
+	signedHeaders := make([]string, 0, len(options.Headers))
+
+	for k := range options.Headers {
+		signedHeaders = append(signedHeaders, strings.ToLower(k))
+	}
+
+	if len(signedHeaders) > 0 {
+		sort.Strings(signedHeaders)
+
+		params.Set(LocalSignedHeadersParam, strings.Join(signedHeaders, ";"))
+	}
+
+	signParams := url.Values{}
+
+	for k, v := range params {
+		signParams[k] = v
+	}
+
+	for k, v := range options.Headers {
+		signParams.Set(strings.ToLower(k), v)
+	}
+
+	signature := b.sign(method, bucket, key, signParams, expires)
+
+	params.Set("Expires", strconv.FormatInt(expires, 10))
+	params.Set("Signature", signature)
+
+	return fmt.Sprintf("%s/%s?%s", b.baseUrl(bucket, options.Domain), (&url.URL{Path: key}).EscapedPath(), params.Encode()), nil
+}
+
+func (b *localBackend) PostPolicyToken(bucket string, key string, options PostPolicyOptions) (*PostPolicyToken, error) {
+	policy, fields, err := buildPostPolicy(bucket, key, options, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostPolicyToken{
+		UploadUrl:   b.baseUrl(bucket, options.Domain),
+		AccessKeyId: b.config.AccessKeyId,
+		Policy:      policy,
+		Signature:   b.hmac(policy),
+		Fields:      fields,
+	}, nil
+}
+
+func (b *localBackend) InitiateMultipartUpload(bucket string, key string, options PutOptions) (string, error) {
+	if _, err := b.objectPath(bucket, key); err != nil {
+		return "", err
+	}
+
+	uploadId := newLocalUploadId()
+	uploadDir := filepath.Join(b.root, localMultipartDir, uploadId)
+
+	if err := os.MkdirAll(uploadDir, 0750); err != nil {
+		return "", err
+	} else if err := os.WriteFile(filepath.Join(uploadDir, localUploadFile), []byte(path.Join(bucket, key)), 0640); err != nil {
+		return "", err
+	}
+
+	return uploadId, nil
+}
+
+func (b *localBackend) UploadPart(bucket string, key string, uploadId string, partNumber int, reader io.Reader, size int64) (*Part, error) {
+	uploadDir, err := b.uploadDir(bucket, key, uploadId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tempFile, err := os.CreateTemp(uploadDir, ".upload-*")
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.Remove(tempFile.Name())
+
+	hash := md5.New()
+	written, err := io.Copy(io.MultiWriter(tempFile, hash), io.LimitReader(reader, size))
+
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return nil, err
+	} else if written != size {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	part := &Part{PartNumber: partNumber, ETag: hex.EncodeToString(hash.Sum(nil)), Size: size}
+
+	if matches, err := filepath.Glob(filepath.Join(uploadDir, fmt.Sprintf("%05d-*", partNumber))); err == nil {
+		for _, match := range matches {
+			os.Remove(match)
+		}
+	}
+
+	if err := os.Rename(tempFile.Name(), filepath.Join(uploadDir, fmt.Sprintf("%05d-%s", partNumber, part.ETag))); err != nil {
+		return nil, err
+	}
+
+	return part, nil
+}
+
+func (b *localBackend) ListParts(bucket string, key string, uploadId string) ([]Part, error) {
+	uploadDir, err := b.uploadDir(bucket, key, uploadId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(uploadDir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]Part, 0, len(entries))
+
+	for _, entry := range entries {
+		name := entry.Name()
+
+		if partNumberStr, etag, ok := strings.Cut(name, "-"); ok && !strings.HasPrefix(name, ".") {
+			if partNumber, err := strconv.Atoi(partNumberStr); err == nil {
+				if fileInfo, err := entry.Info(); err == nil {
+					parts = append(parts, Part{PartNumber: partNumber, ETag: etag, Size: fileInfo.Size()})
+				}
+			}
+		}
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+
+	return parts, nil
+}
+
+func (b *localBackend) CompleteMultipartUpload(bucket string, key string, uploadId string, parts []Part) error {
+	uploadDir, err := b.uploadDir(bucket, key, uploadId)
+
+	if err != nil {
+		return err
+	}
+
+	readers := make([]io.Reader, 0, len(parts))
+
+	for _, part := range parts {
+		if f, err := os.Open(filepath.Join(uploadDir, fmt.Sprintf("%05d-%s", part.PartNumber, strings.Trim(part.ETag, `"`)))); err != nil {
+			return fmt.Errorf("分片（%d）不存在：%w", part.PartNumber, err)
+		} else {
+			defer f.Close()
+
+			readers = append(readers, f)
+		}
+	}
+
+	if err := b.Put(bucket, key, io.MultiReader(readers...), PutOptions{}); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(uploadDir)
+}
+
+func (b *localBackend) AbortMultipartUpload(bucket string, key string, uploadId string) error {
+	if uploadDir, err := b.uploadDir(bucket, key, uploadId); err != nil {
+		return err
+	} else {
+		return os.RemoveAll(uploadDir)
+	}
+}
+
+func (b *localBackend) VerifyURL(method string, bucket string, key string, params url.Values, expires int64, signature string) error {
+	if !hmac.Equal([]byte(signature), []byte(b.sign(method, bucket, key, params, expires))) {
+		return ErrInvalidSignature
+	} else if time.Now().Unix() > expires {
+		return ErrSignatureExpired
+	}
+
+	return nil
+}
+
+func (b *localBackend) VerifyPostPolicy(bucket string, key string, fields map[string]string, size int64, policy string, signature string) error {
+	if !hmac.Equal([]byte(signature), []byte(b.hmac(policy))) {
+		return ErrInvalidSignature
+	}
+
+	policyDocument := struct {
+		Expiration string `json:"expiration"`
+		Conditions []any  `json:"conditions"`
+	}{}
+
+	if bytes, err := base64.StdEncoding.DecodeString(policy); err != nil {
+		return ErrInvalidSignature
+	} else if err := json.Unmarshal(bytes, &policyDocument); err != nil {
+		return ErrInvalidSignature
+	} else if expiration, err := time.Parse(time.RFC3339Nano, policyDocument.Expiration); err != nil || time.Now().After(expiration) {
+		return ErrSignatureExpired
+	}
+
+	values := map[string]string{"bucket": bucket, "key": key}
+
+	for k, v := range fields {
+		if k = strings.ToLower(k); k != "bucket" && k != "key" {
+			values[k] = v
+		}
+	}
+
+	for _, condition := range policyDocument.Conditions {
+		switch condition := condition.(type) {
+		case map[string]any:
+			for k, v := range condition {
+				if err := checkPolicyCondition("eq", k, v, values); err != nil {
+					return err
+				}
+			}
+		case []any:
+			if len(condition) != 3 {
+				return ErrPolicyViolation
+			} else if condition[0] == "content-length-range" {
+				if min, ok := condition[1].(float64); !ok || size < int64(min) {
+					return ErrPolicyViolation
+				} else if max, ok := condition[2].(float64); !ok || size > int64(max) {
+					return ErrPolicyViolation
+				}
+			} else if op, ok := condition[0].(string); !ok {
+				return ErrPolicyViolation
+			} else if name, ok := condition[1].(string); !ok {
+				return ErrPolicyViolation
+			} else if err := checkPolicyCondition(op, strings.TrimPrefix(name, "$"), condition[2], values); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// bucket和key不匹配说明策略并非为该对象签发，按签名无效处理
+func checkPolicyCondition(op string, name string, expected any, values map[string]string) error {
+	name = strings.ToLower(name)
+	value, ok := expected.(string)
+	actual := values[name]
+
+	if ok && ((op == "eq" && actual == value) || (op == "starts-with" && strings.HasPrefix(actual, value))) {
+		return nil
+	} else if name == "bucket" || name == "key" {
+		return ErrInvalidSignature
+	} else {
+		return ErrPolicyViolation
+	}
+}
+
+func (b *localBackend) objectPath(bucket string, key string) (string, error) {
+	bucketRoot := filepath.Join(b.root, bucket)
+	file := filepath.Join(bucketRoot, filepath.FromSlash(key))
+
+	if bucket == "" || strings.HasPrefix(bucket, ".") || strings.ContainsAny(bucket, `/\`) || !strings.HasPrefix(file, bucketRoot+string(filepath.Separator)) {
+		return "", ErrInvalidKey
+	}
+
+	return file, nil
+}
+
+func (b *localBackend) uploadDir(bucket string, key string, uploadId string) (string, error) {
+	if uploadId == "" || strings.ContainsAny(uploadId, `/\.`) {
+		return "", ErrInvalidUploadId
+	}
+
+	uploadDir := filepath.Join(b.root, localMultipartDir, uploadId)
+
+	if bytes, err := os.ReadFile(filepath.Join(uploadDir, localUploadFile)); err != nil || string(bytes) != path.Join(bucket, key) {
+		return "", ErrInvalidUploadId
+	}
+
+	return uploadDir, nil
+}
+
+func (b *localBackend) baseUrl(bucket string, domain string) string {
+	if domain != "" {
+		return "//" + domain
+	}
+
+	return fmt.Sprintf("%s/%s", LocalRoutePrefix, url.PathEscape(bucket))
+}
+
+// 附加查询参数按键排序后追加到待签名字符串末尾
+func (b *localBackend) sign(method string, bucket string, key string, params url.Values, expires int64) string {
+	stringToSign := fmt.Sprintf("%s\n%d\n/%s/%s", method, expires, bucket, key)
+
+	if len(params) > 0 {
+		stringToSign += "\n" + params.Encode()
+	}
+
+	return b.hmac(stringToSign)
+}
+
+func (b *localBackend) hmac(stringToSign string) string {
+	mac := hmac.New(sha256.New, []byte(b.config.AccessKeySecret))
+	mac.Write([]byte(stringToSign))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newLocalUploadId() string {
+	bytes := make([]byte, 16)
+
+	rand.Read(bytes)
+
+	return hex.EncodeToString(bytes)
+}
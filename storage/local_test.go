@@ -0,0 +1,259 @@
+package storage
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func Test_localBackend(t *testing.T) {
+	backend, err := NewLocalBackend(Config{Provider: ProviderLocal, Endpoint: t.TempDir(), AccessKeySecret: "secret"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewLocalBackend(Config{Provider: ProviderLocal, Endpoint: t.TempDir()}); err == nil {
+		t.Error("expected empty access key secret rejected")
+	}
+
+	if err := backend.Put("test", "images/a.png", strings.NewReader("hello"), PutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if objectInfo, err := backend.Head("test", "images/a.png"); err != nil {
+		t.Fatal(err)
+	} else if objectInfo.Size != 5 || objectInfo.ContentType != "image/png" {
+		t.Errorf("unexpected object info: %+v", objectInfo)
+	}
+
+	if reader, err := backend.Get("test", "images/a.png"); err != nil {
+		t.Fatal(err)
+	} else {
+		bytes, _ := io.ReadAll(reader)
+
+		reader.Close()
+
+		if string(bytes) != "hello" {
+			t.Errorf("unexpected content: %s", bytes)
+		}
+	}
+
+	if reader, err := backend.GetRange("test", "images/a.png", 1, 3); err != nil {
+		t.Fatal(err)
+	} else {
+		bytes, _ := io.ReadAll(reader)
+
+		reader.Close()
+
+		if string(bytes) != "ell" {
+			t.Errorf("unexpected range content: %s", bytes)
+		}
+	}
+
+	if err := backend.Copy("test", "images/a.png", "images/b.png"); err != nil {
+		t.Fatal(err)
+	}
+
+	if listResult, err := backend.List("test", "images/", "", 1); err != nil {
+		t.Fatal(err)
+	} else if len(listResult.Objects) != 1 || !listResult.IsTruncated || listResult.NextMarker != "images/a.png" {
+		t.Errorf("unexpected list result: %+v", listResult)
+	}
+
+	if err := backend.Put("test", "images/c.png", strings.NewReader("hello"), PutOptions{ContentMD5: "XUFAKrxLKna5cZ2REBfFkg=="}); err != nil {
+		t.Errorf("expected matching md5, got %v", err)
+	}
+
+	if err := backend.Put("test", "images/c.png", strings.NewReader("hellO"), PutOptions{ContentMD5: "XUFAKrxLKna5cZ2REBfFkg=="}); err != ErrBadDigest {
+		t.Errorf("expected ErrBadDigest, got %v", err)
+	}
+
+	if err := backend.Put("test", "../escape.txt", strings.NewReader("x"), PutOptions{}); err != ErrInvalidKey {
+		t.Errorf("expected ErrInvalidKey, got %v", err)
+	}
+
+	if err := backend.Delete("test", "images/b.png"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := backend.Head("test", "images/b.png"); err != ErrObjectNotFound {
+		t.Errorf("expected ErrObjectNotFound, got %v", err)
+	}
+}
+
+func Test_localBackend_SignURL(t *testing.T) {
+	backend, _ := NewLocalBackend(Config{Provider: ProviderLocal, Endpoint: t.TempDir(), AccessKeySecret: "secret"})
+	verifier := backend.(SignatureVerifier)
+
+	signedURL, err := backend.SignURL("test", "images/a b.png", SignOptions{ExpiredInSec: 60, Domain: "127.0.0.1:6103/v1/files/local/test"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(signedURL, "//127.0.0.1:6103/v1/files/local/test/images/a%20b.png?") {
+		t.Errorf("unexpected signed url: %s", signedURL)
+	}
+
+	u, _ := url.Parse("http:" + signedURL)
+	expires, _ := strconv.ParseInt(u.Query().Get("Expires"), 10, 64)
+
+	if err := verifier.VerifyURL(http.MethodGet, "test", "images/a b.png", nil, expires, u.Query().Get("Signature")); err != nil {
+		t.Errorf("expected valid signature, got %v", err)
+	}
+
+	if err := verifier.VerifyURL(http.MethodGet, "test", "images/c.png", nil, expires, u.Query().Get("Signature")); err != ErrInvalidSignature {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+
+	if err := verifier.VerifyURL(http.MethodGet, "test", "images/a b.png", nil, expires+1, u.Query().Get("Signature")); err != ErrInvalidSignature {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+
+	partURL, _ := backend.SignURL("test", "videos/a.mp4", SignOptions{Method: http.MethodPut, ExpiredInSec: 60, Params: map[string]string{"uploadId": "abc", "partNumber": "1"}})
+	u, _ = url.Parse("http:" + partURL)
+	expires, _ = strconv.ParseInt(u.Query().Get("Expires"), 10, 64)
+	params := url.Values{"uploadId": {"abc"}, "partNumber": {"1"}}
+
+	if err := verifier.VerifyURL(http.MethodPut, "test", "videos/a.mp4", params, expires, u.Query().Get("Signature")); err != nil {
+		t.Errorf("expected valid part signature, got %v", err)
+	}
+
+	params.Set("partNumber", "2")
+
+	if err := verifier.VerifyURL(http.MethodPut, "test", "videos/a.mp4", params, expires, u.Query().Get("Signature")); err != ErrInvalidSignature {
+		t.Errorf("expected ErrInvalidSignature for other part, got %v", err)
+	}
+
+	putURL, _ := backend.SignURL("test", "images/a.png", SignOptions{Method: http.MethodPut, ExpiredInSec: 60, Headers: map[string]string{"Content-Type": "image/png"}})
+	u, _ = url.Parse("http:" + putURL)
+	expires, _ = strconv.ParseInt(u.Query().Get("Expires"), 10, 64)
+	params = url.Values{LocalSignedHeadersParam: {u.Query().Get(LocalSignedHeadersParam)}, "content-type": {"image/png"}}
+
+	if u.Query().Get(LocalSignedHeadersParam) != "content-type" {
+		t.Errorf("unexpected signed headers: %s", putURL)
+	}
+
+	if err := verifier.VerifyURL(http.MethodPut, "test", "images/a.png", params, expires, u.Query().Get("Signature")); err != nil {
+		t.Errorf("expected valid put signature, got %v", err)
+	}
+
+	params.Set("content-type", "text/html")
+
+	if err := verifier.VerifyURL(http.MethodPut, "test", "images/a.png", params, expires, u.Query().Get("Signature")); err != ErrInvalidSignature {
+		t.Errorf("expected ErrInvalidSignature for other content type, got %v", err)
+	}
+
+	policyToken, _ := backend.PostPolicyToken("test", "images/a.png", PostPolicyOptions{ExpiredInSec: 60})
+
+	if err := verifier.VerifyPostPolicy("test", "images/a.png", nil, 0, policyToken.Policy, policyToken.Signature); err != nil {
+		t.Errorf("expected valid policy, got %v", err)
+	}
+
+	if err := verifier.VerifyPostPolicy("test", "images/other.png", nil, 0, policyToken.Policy, policyToken.Signature); err != ErrInvalidSignature {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+func Test_localBackend_VerifyPostPolicy(t *testing.T) {
+	backend, _ := NewLocalBackend(Config{Provider: ProviderLocal, Endpoint: t.TempDir(), AccessKeySecret: "secret"})
+	verifier := backend.(SignatureVerifier)
+
+	policyToken, err := backend.PostPolicyToken("test", "images/a.png", PostPolicyOptions{
+		ExpiredInSec:        60,
+		MaxSize:             1024,
+		ContentTypePrefix:   "image/",
+		ContentDisposition:  "inline",
+		SuccessActionStatus: "201",
+		Meta:                map[string]string{"owner": "alice"},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if policyToken.Fields["x-oss-meta-owner"] != "alice" || policyToken.Fields["success_action_status"] != "201" || policyToken.Fields["Content-Disposition"] != "inline" {
+		t.Errorf("unexpected fields: %v", policyToken.Fields)
+	}
+
+	fields := map[string]string{"Content-Type": "image/png", "content-disposition": "inline", "success_action_status": "201", "x-oss-meta-owner": "alice"}
+
+	if err := verifier.VerifyPostPolicy("test", "images/a.png", fields, 512, policyToken.Policy, policyToken.Signature); err != nil {
+		t.Errorf("expected valid policy, got %v", err)
+	}
+
+	if err := verifier.VerifyPostPolicy("test", "images/a.png", fields, 2048, policyToken.Policy, policyToken.Signature); err != ErrPolicyViolation {
+		t.Errorf("expected ErrPolicyViolation for oversized file, got %v", err)
+	}
+
+	fields["Content-Type"] = "application/x-msdownload"
+
+	if err := verifier.VerifyPostPolicy("test", "images/a.png", fields, 512, policyToken.Policy, policyToken.Signature); err != ErrPolicyViolation {
+		t.Errorf("expected ErrPolicyViolation for content type, got %v", err)
+	}
+
+	fields["Content-Type"] = "image/png"
+	fields["x-oss-meta-owner"] = "bob"
+
+	if err := verifier.VerifyPostPolicy("test", "images/a.png", fields, 512, policyToken.Policy, policyToken.Signature); err != ErrPolicyViolation {
+		t.Errorf("expected ErrPolicyViolation for meta, got %v", err)
+	}
+}
+
+func Test_localBackend_MultipartUpload(t *testing.T) {
+	backend, err := NewLocalBackend(Config{Provider: ProviderLocal, Endpoint: t.TempDir(), AccessKeySecret: "secret"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uploadId, err := backend.InitiateMultipartUpload("test", "videos/a.mp4", PutOptions{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := backend.UploadPart("test", "videos/a.mp4", uploadId, 2, strings.NewReader("world"), 5); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := backend.UploadPart("test", "videos/a.mp4", uploadId, 1, strings.NewReader("hello "), 6); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := backend.UploadPart("test", "videos/b.mp4", uploadId, 1, strings.NewReader("x"), 1); err != ErrInvalidUploadId {
+		t.Errorf("expected ErrInvalidUploadId, got %v", err)
+	}
+
+	parts, err := backend.ListParts("test", "videos/a.mp4", uploadId)
+
+	if err != nil {
+		t.Fatal(err)
+	} else if len(parts) != 2 || parts[0].PartNumber != 1 || parts[1].Size != 5 {
+		t.Fatalf("unexpected parts: %+v", parts)
+	}
+
+	if err := backend.CompleteMultipartUpload("test", "videos/a.mp4", uploadId, parts); err != nil {
+		t.Fatal(err)
+	}
+
+	if reader, err := backend.Get("test", "videos/a.mp4"); err != nil {
+		t.Fatal(err)
+	} else {
+		bytes, _ := io.ReadAll(reader)
+
+		reader.Close()
+
+		if string(bytes) != "hello world" {
+			t.Errorf("unexpected content: %s", bytes)
+		}
+	}
+
+	if _, err := backend.ListParts("test", "videos/a.mp4", uploadId); err != ErrInvalidUploadId {
+		t.Errorf("expected completed upload to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,400 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/easynet-cn/file-service/log"
+	"go.uber.org/zap"
+)
+
+const (
+	ossV4SigningAlgorithm = "OSS4-HMAC-SHA256"
+	ossV4DateFormat       = "20060102"
+	ossV4TimeFormat       = "20060102T150405Z"
+)
+
+type ossBackend struct {
+	config Config
+	client *oss.Client
+}
+
+func NewOssBackend(config Config) (Backend, error) {
+	clientOptions := []oss.ClientOption{oss.EnableCRC(true)}
+
+	if config.SignVersion == SignVersionV4 {
+		if config.Region = strings.TrimPrefix(config.Region, "oss-"); config.Region == "" {
+			return nil, errors.New("V4签名必须指定区域")
+		}
+
+		clientOptions = append(clientOptions, oss.AuthVersion(oss.AuthV4), oss.Region(config.Region))
+	} else if config.SignVersion != "" && config.SignVersion != SignVersionV1 {
+		return nil, fmt.Errorf("不支持的签名版本：%s", config.SignVersion)
+	}
+
+	if client, err := oss.New(config.Endpoint, config.AccessKeyId, config.AccessKeySecret, clientOptions...); err != nil {
+		return nil, err
+	} else {
+		return &ossBackend{config: config, client: client}, nil
+	}
+}
+
+func (b *ossBackend) Put(bucket string, key string, reader io.Reader, options PutOptions) error {
+	if ossBucket, err := b.client.Bucket(bucket); err != nil {
+		return err
+	} else {
+		ossOptions := make([]oss.Option, 0, 2)
+
+		if options.ContentType != "" {
+			ossOptions = append(ossOptions, oss.ContentType(options.ContentType))
+		}
+
+		if options.ContentMD5 != "" {
+			ossOptions = append(ossOptions, oss.ContentMD5(options.ContentMD5))
+		}
+
+		return ossBucket.PutObject(key, reader, ossOptions...)
+	}
+}
+
+func (b *ossBackend) PutFile(bucket string, key string, file string) error {
+	ossBucket, err := b.client.Bucket(bucket)
+
+	if err != nil {
+		return err
+	}
+
+	retryCount := 0
+	retryMax := 3
+
+	if err = ossBucket.UploadFile(key, file, 100*1024, oss.Routines(3), oss.Checkpoint(true, "")); err != nil {
+		log.Logger.Error("bucket.UploadFile", zap.String("fileKey", key), zap.String("file", file), zap.Error(err))
+
+		for retryCount < retryMax && err != nil {
+			if err = ossBucket.UploadFile(key, file, 100*1024, oss.Routines(3), oss.Checkpoint(true, "")); err != nil {
+				log.Logger.Error("bucket.UploadFile", zap.String("fileKey", key), zap.String("file", file), zap.Error(err))
+			}
+
+			retryCount++
+		}
+	}
+
+	return err
+}
+
+func (b *ossBackend) Get(bucket string, key string) (io.ReadCloser, error) {
+	if ossBucket, err := b.client.Bucket(bucket); err != nil {
+		return nil, err
+	} else {
+		return ossBucket.GetObject(key)
+	}
+}
+
+func (b *ossBackend) GetRange(bucket string, key string, offset int64, length int64) (io.ReadCloser, error) {
+	if ossBucket, err := b.client.Bucket(bucket); err != nil {
+		return nil, err
+	} else {
+		return ossBucket.GetObject(key, oss.Range(offset, offset+length-1))
+	}
+}
+
+func (b *ossBackend) Head(bucket string, key string) (*ObjectInfo, error) {
+	if ossBucket, err := b.client.Bucket(bucket); err != nil {
+		return nil, err
+	} else if header, err := ossBucket.GetObjectDetailedMeta(key); err != nil {
+		if serviceError := (oss.ServiceError{}); errors.As(err, &serviceError) && serviceError.StatusCode == http.StatusNotFound {
+			return nil, ErrObjectNotFound
+		}
+
+		return nil, err
+	} else {
+		return ossHeaderToObjectInfo(key, header), nil
+	}
+}
+
+func (b *ossBackend) Delete(bucket string, key string) error {
+	if ossBucket, err := b.client.Bucket(bucket); err != nil {
+		return err
+	} else {
+		return ossBucket.DeleteObject(key)
+	}
+}
+
+func (b *ossBackend) Copy(bucket string, srcKey string, destKey string) error {
+	if ossBucket, err := b.client.Bucket(bucket); err != nil {
+		return err
+	} else {
+		_, err := ossBucket.CopyObject(srcKey, destKey)
+
+		return err
+	}
+}
+
+func (b *ossBackend) List(bucket string, prefix string, marker string, maxKeys int) (*ListResult, error) {
+	if ossBucket, err := b.client.Bucket(bucket); err != nil {
+		return nil, err
+	} else if result, err := ossBucket.ListObjects(oss.Prefix(prefix), oss.Marker(marker), oss.MaxKeys(maxKeys)); err != nil {
+		return nil, err
+	} else {
+		listResult := &ListResult{
+			Objects:     make([]ObjectInfo, len(result.Objects)),
+			NextMarker:  result.NextMarker,
+			IsTruncated: result.IsTruncated,
+		}
+
+		for i, object := range result.Objects {
+			listResult.Objects[i] = ObjectInfo{
+				Key:          object.Key,
+				Size:         object.Size,
+				ETag:         strings.Trim(object.ETag, `"`),
+				LastModified: object.LastModified,
+			}
+		}
+
+		return listResult, nil
+	}
+}
+
+func (b *ossBackend) SignURL(bucket string, key string, options SignOptions) (string, error) {
+	ossBucket, err := b.client.Bucket(bucket)
+
+	if err != nil {
+		return "", err
+	}
+
+	method := oss.HTTPGet
+
+	if options.Method != "" {
+		method = oss.HTTPMethod(options.Method)
+	}
+
+	ossOptions := make([]oss.Option, 0, 1+len(options.Params)+len(options.Headers))
+
+	if options.Process != "" {
+		ossOptions = append(ossOptions, oss.Process(options.Process))
+	}
+
+	for k, v := range options.Params {
+		ossOptions = append(ossOptions, oss.AddParam(k, v))
+	}
+
+	for k, v := range options.Headers {
+		ossOptions = append(ossOptions, oss.SetHeader(http.CanonicalHeaderKey(k), v))
+	}
+
+	signedURL, err := ossBucket.SignURL(key, method, options.ExpiredInSec, ossOptions...)
+
+	if err != nil {
+		return "", err
+	}
+
+	if signedURL != "" && strings.Contains(signedURL, "//") {
+		str := signedURL[strings.Index(signedURL, "//"):]
+
+		if options.Domain != "" {
+			str = strings.Replace(str, fmt.Sprintf("%s.%s", bucket, b.config.Endpoint), options.Domain, 1)
+		}
+
+		return str, nil
+	}
+
+	return signedURL, nil
+}
+
+func (b *ossBackend) PostPolicyToken(bucket string, key string, options PostPolicyOptions) (*PostPolicyToken, error) {
+	uploadUrl := fmt.Sprintf("//%s.%s", bucket, b.config.Endpoint)
+
+	if options.Domain != "" {
+		uploadUrl = fmt.Sprintf("//%s", options.Domain)
+	}
+
+	if b.config.SignVersion == SignVersionV4 {
+		return b.postPolicyTokenV4(bucket, key, uploadUrl, options, time.Now().UTC())
+	}
+
+	policy, fields, err := buildPostPolicy(bucket, key, options, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha1.New, []byte(b.config.AccessKeySecret))
+	mac.Write([]byte(policy))
+
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	// V1签名的表单必须携带秘钥ID，V4签名的秘钥ID包含在x-oss-credential中
+	fields["OSSAccessKeyId"] = b.config.AccessKeyId
+
+	return &PostPolicyToken{
+		UploadUrl:   uploadUrl,
+		AccessKeyId: b.config.AccessKeyId,
+		Policy:      policy,
+		Signature:   signature,
+		Fields:      fields,
+	}, nil
+}
+
+// V4签名的凭证、时间和签名版本需要作为表单字段提交，同时写入策略条件
+func (b *ossBackend) postPolicyTokenV4(bucket string, key string, uploadUrl string, options PostPolicyOptions, now time.Time) (*PostPolicyToken, error) {
+	date := now.Format(ossV4DateFormat)
+	signFields := map[string]string{
+		"x-oss-signature-version": ossV4SigningAlgorithm,
+		"x-oss-credential":        fmt.Sprintf("%s/%s/%s/oss/aliyun_v4_request", b.config.AccessKeyId, date, b.config.Region),
+		"x-oss-date":              now.Format(ossV4TimeFormat),
+	}
+
+	policy, fields, err := buildPostPolicy(bucket, key, options, signFields)
+
+	if err != nil {
+		return nil, err
+	}
+
+	signature := ossSignV4(b.config.AccessKeySecret, date, b.config.Region, policy)
+
+	fields["x-oss-signature"] = signature
+
+	return &PostPolicyToken{
+		UploadUrl:   uploadUrl,
+		AccessKeyId: b.config.AccessKeyId,
+		Policy:      policy,
+		Signature:   signature,
+		Fields:      fields,
+	}, nil
+}
+
+func (b *ossBackend) InitiateMultipartUpload(bucket string, key string, options PutOptions) (string, error) {
+	if ossBucket, err := b.client.Bucket(bucket); err != nil {
+		return "", err
+	} else {
+		ossOptions := make([]oss.Option, 0, 1)
+
+		if options.ContentType != "" {
+			ossOptions = append(ossOptions, oss.ContentType(options.ContentType))
+		}
+
+		if imur, err := ossBucket.InitiateMultipartUpload(key, ossOptions...); err != nil {
+			return "", err
+		} else {
+			return imur.UploadID, nil
+		}
+	}
+}
+
+func (b *ossBackend) UploadPart(bucket string, key string, uploadId string, partNumber int, reader io.Reader, size int64) (*Part, error) {
+	if ossBucket, err := b.client.Bucket(bucket); err != nil {
+		return nil, err
+	} else if part, err := ossBucket.UploadPart(ossMultipartUpload(bucket, key, uploadId), reader, size, partNumber); err != nil {
+		return nil, err
+	} else {
+		return &Part{PartNumber: part.PartNumber, ETag: strings.Trim(part.ETag, `"`), Size: size}, nil
+	}
+}
+
+func (b *ossBackend) ListParts(bucket string, key string, uploadId string) ([]Part, error) {
+	ossBucket, err := b.client.Bucket(bucket)
+
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]Part, 0)
+	partNumberMarker := 0
+
+	for {
+		result, err := ossBucket.ListUploadedParts(ossMultipartUpload(bucket, key, uploadId), oss.PartNumberMarker(partNumberMarker))
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, part := range result.UploadedParts {
+			parts = append(parts, Part{PartNumber: part.PartNumber, ETag: strings.Trim(part.ETag, `"`), Size: int64(part.Size)})
+		}
+
+		if !result.IsTruncated {
+			break
+		}
+
+		if partNumberMarker, err = strconv.Atoi(result.NextPartNumberMarker); err != nil {
+			return nil, err
+		}
+	}
+
+	return parts, nil
+}
+
+func (b *ossBackend) CompleteMultipartUpload(bucket string, key string, uploadId string, parts []Part) error {
+	if ossBucket, err := b.client.Bucket(bucket); err != nil {
+		return err
+	} else {
+		uploadParts := make([]oss.UploadPart, len(parts))
+
+		for i, part := range parts {
+			uploadParts[i] = oss.UploadPart{PartNumber: part.PartNumber, ETag: part.ETag}
+		}
+
+		_, err := ossBucket.CompleteMultipartUpload(ossMultipartUpload(bucket, key, uploadId), uploadParts)
+
+		return err
+	}
+}
+
+func (b *ossBackend) AbortMultipartUpload(bucket string, key string, uploadId string) error {
+	if ossBucket, err := b.client.Bucket(bucket); err != nil {
+		return err
+	} else {
+		return ossBucket.AbortMultipartUpload(ossMultipartUpload(bucket, key, uploadId))
+	}
+}
+
+func ossMultipartUpload(bucket string, key string, uploadId string) oss.InitiateMultipartUploadResult {
+	return oss.InitiateMultipartUploadResult{Bucket: bucket, Key: key, UploadID: uploadId}
+}
+
+func ossHeaderToObjectInfo(key string, header http.Header) *ObjectInfo {
+	objectInfo := &ObjectInfo{
+		Key:         key,
+		ContentType: header.Get("Content-Type"),
+		ETag:        strings.Trim(header.Get("ETag"), `"`),
+		Crc64:       header.Get(oss.HTTPHeaderOssCRC64),
+	}
+
+	if size, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64); err == nil {
+		objectInfo.Size = size
+	}
+
+	if lastModified, err := http.ParseTime(header.Get("Last-Modified")); err == nil {
+		objectInfo.LastModified = lastModified
+	}
+
+	return objectInfo
+}
+
+// 签名秘钥由aliyun_v4+AccessKeySecret依次对日期、区域、产品和请求类型做HMAC-SHA256派生
+func ossSignV4(accessKeySecret string, date string, region string, stringToSign string) string {
+	signingKey := hmacSHA256([]byte("aliyun_v4"+accessKeySecret), date)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, "oss")
+	signingKey = hmacSHA256(signingKey, "aliyun_v4_request")
+
+	return hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ossSignV4(t *testing.T) {
+	policy := "eyJleHBpcmF0aW9uIjoiMjAyMy0xMi0wM1QxMzowMDowMC4wMDBaIiwiY29uZGl0aW9ucyI6W3siYnVja2V0IjoiZXhhbXBsZWJ1Y2tldCJ9XX0="
+
+	if signature := ossSignV4("yourAccessKeySecret", "20231203", "cn-hangzhou", policy); signature != "44eab9192d2999223d4f5e7e2a16dc12091d31cca05b935442f8ad618050415d" {
+		t.Errorf("unexpected signature: %s", signature)
+	}
+}
+
+func Test_ossBackend_PostPolicyTokenV4(t *testing.T) {
+	backend, err := NewOssBackend(Config{Endpoint: "oss-cn-hangzhou.aliyuncs.com", AccessKeyId: "ak", AccessKeySecret: "yourAccessKeySecret", Region: "oss-cn-hangzhou", SignVersion: SignVersionV4})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2023, 12, 3, 12, 12, 12, 0, time.UTC)
+	policyToken, err := backend.(*ossBackend).postPolicyTokenV4("examplebucket", "a.png", "//examplebucket.oss-cn-hangzhou.aliyuncs.com", PostPolicyOptions{ExpiredInSec: 60}, now)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if policyToken.Fields["x-oss-credential"] != "ak/20231203/cn-hangzhou/oss/aliyun_v4_request" || policyToken.Fields["x-oss-date"] != "20231203T121212Z" || policyToken.Fields["x-oss-signature-version"] != "OSS4-HMAC-SHA256" {
+		t.Errorf("unexpected fields: %v", policyToken.Fields)
+	}
+
+	if policyToken.Signature != ossSignV4("yourAccessKeySecret", "20231203", "cn-hangzhou", policyToken.Policy) || policyToken.Fields["x-oss-signature"] != policyToken.Signature {
+		t.Errorf("unexpected signature: %s", policyToken.Signature)
+	}
+
+	policy, _ := base64.StdEncoding.DecodeString(policyToken.Policy)
+
+	if !strings.Contains(string(policy), `{"x-oss-credential":"ak/20231203/cn-hangzhou/oss/aliyun_v4_request"}`) || !strings.Contains(string(policy), `{"x-oss-date":"20231203T121212Z"}`) {
+		t.Errorf("sign fields should be policy conditions: %s", policy)
+	}
+}
+
+func Test_ossBackend_PostPolicyToken(t *testing.T) {
+	backend, err := NewOssBackend(Config{Endpoint: "oss-cn-hangzhou.aliyuncs.com", AccessKeyId: "ak", AccessKeySecret: "sk", Region: "cn-hangzhou"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if policyToken, err := backend.PostPolicyToken("examplebucket", "a.png", PostPolicyOptions{ExpiredInSec: 60}); err != nil {
+		t.Fatal(err)
+	} else if policyToken.Fields["OSSAccessKeyId"] != "ak" || policyToken.AccessKeyId != "ak" || policyToken.Signature == "" {
+		t.Errorf("unexpected v1 fields: %v", policyToken.Fields)
+	}
+
+	callback := &Callback{Url: "https://example.com/callback", Body: "fileId=1", BodyType: "application/x-www-form-urlencoded"}
+	encoded, _ := callback.Encode()
+
+	if policyToken, err := backend.PostPolicyToken("examplebucket", "a.png", PostPolicyOptions{ExpiredInSec: 60, Callback: callback}); err != nil {
+		t.Fatal(err)
+	} else if policy, _ := base64.StdEncoding.DecodeString(policyToken.Policy); policyToken.Fields["callback"] != encoded || !strings.Contains(string(policy), `{"callback":"`+encoded+`"}`) {
+		t.Errorf("callback should be a policy condition: %s", policy)
+	}
+}
+
+func Test_ossBackend_SignURL(t *testing.T) {
+	for _, signVersion := range []string{SignVersionV1, SignVersionV4} {
+		backend, err := NewOssBackend(Config{Endpoint: "oss-cn-hangzhou.aliyuncs.com", AccessKeyId: "ak", AccessKeySecret: "sk", Region: "cn-hangzhou", SignVersion: signVersion})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		signedURL, err := backend.SignURL("examplebucket", "a.png", SignOptions{ExpiredInSec: 60})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		u, _ := url.Parse("https:" + signedURL)
+		query := u.Query()
+
+		if putURL, err := backend.SignURL("examplebucket", "a.png", SignOptions{Method: "PUT", ExpiredInSec: 60, Headers: map[string]string{"Content-Type": "image/png"}}); err != nil {
+			t.Fatal(err)
+		} else if otherURL, _ := backend.SignURL("examplebucket", "a.png", SignOptions{Method: "PUT", ExpiredInSec: 60, Headers: map[string]string{"Content-Type": "text/html"}}); putURL == otherURL {
+			t.Errorf("content type should be bound into %s signature", signVersion)
+		}
+
+		if signVersion == SignVersionV4 {
+			if query.Get("x-oss-signature-version") != "OSS4-HMAC-SHA256" || !strings.HasPrefix(query.Get("x-oss-credential"), "ak/") || !strings.HasSuffix(query.Get("x-oss-credential"), "/cn-hangzhou/oss/aliyun_v4_request") || len(query.Get("x-oss-signature")) != 64 {
+				t.Errorf("unexpected v4 signed url: %s", signedURL)
+			}
+		} else if query.Get("OSSAccessKeyId") != "ak" || query.Get("Signature") == "" {
+			t.Errorf("unexpected v1 signed url: %s", signedURL)
+		}
+	}
+
+	if _, err := NewOssBackend(Config{Endpoint: "oss-cn-hangzhou.aliyuncs.com", SignVersion: SignVersionV4}); err == nil {
+		t.Error("expected error for v4 without region")
+	}
+
+	if _, err := NewOssBackend(Config{Endpoint: "oss-cn-hangzhou.aliyuncs.com", SignVersion: "v3"}); err == nil {
+		t.Error("expected error for unsupported sign version")
+	}
+}
+
+func Test_ossHeaderToObjectInfo(t *testing.T) {
+	header := http.Header{}
+
+	header.Set("Content-Length", "5")
+	header.Set("ETag", `"5D41402ABC4B2A76B9719D911017C592"`)
+	header.Set("X-Oss-Hash-Crc64ecma", "1234567890")
+
+	if objectInfo := ossHeaderToObjectInfo("a.txt", header); objectInfo.Size != 5 || objectInfo.ETag != "5D41402ABC4B2A76B9719D911017C592" || objectInfo.Crc64 != "1234567890" {
+		t.Errorf("unexpected object info: %+v", objectInfo)
+	}
+}
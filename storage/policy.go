@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+const (
+	ossMetaPrefix     = "x-oss-meta-"
+	maxPostObjectSize = 5 * 1024 * 1024 * 1024
+)
+
+// 生成OSS格式的POST策略，需要前端随表单提交的字段一并返回
+func buildPostPolicy(bucket string, key string, options PostPolicyOptions, signFields map[string]string) (string, map[string]string, error) {
+	fields := make(map[string]string)
+	conditions := []any{
+		map[string]string{"bucket": bucket},
+		[]string{"eq", "$key", key},
+	}
+
+	signKeys := make([]string, 0, len(signFields))
+
+	for k := range signFields {
+		signKeys = append(signKeys, k)
+	}
+
+	sort.Strings(signKeys)
+
+	for _, k := range signKeys {
+		conditions = append(conditions, map[string]string{k: signFields[k]})
+		fields[k] = signFields[k]
+	}
+
+	if options.MinSize > 0 || options.MaxSize > 0 {
+		conditions = append(conditions, []any{"content-length-range", options.MinSize, postPolicyMaxSize(options)})
+	}
+
+	if options.ContentType != "" {
+		conditions = append(conditions, []string{"eq", "$Content-Type", options.ContentType})
+		fields["Content-Type"] = options.ContentType
+	} else if options.ContentTypePrefix != "" {
+		conditions = append(conditions, []string{"starts-with", "$Content-Type", options.ContentTypePrefix})
+	}
+
+	if options.ContentDisposition != "" {
+		conditions = append(conditions, []string{"eq", "$Content-Disposition", options.ContentDisposition})
+		fields["Content-Disposition"] = options.ContentDisposition
+	}
+
+	if options.SuccessActionStatus != "" {
+		conditions = append(conditions, map[string]string{"success_action_status": options.SuccessActionStatus})
+		fields["success_action_status"] = options.SuccessActionStatus
+	}
+
+	metaKeys := make([]string, 0, len(options.Meta))
+
+	for k := range options.Meta {
+		metaKeys = append(metaKeys, k)
+	}
+
+	sort.Strings(metaKeys)
+
+	for _, k := range metaKeys {
+		conditions = append(conditions, []string{"eq", "$" + ossMetaPrefix + k, options.Meta[k]})
+		fields[ossMetaPrefix+k] = options.Meta[k]
+	}
+
+	// 回调参数写入策略条件，防止客户端替换回调地址或回调体
+	if options.Callback != nil {
+		if callback, err := options.Callback.Encode(); err != nil {
+			return "", nil, err
+		} else {
+			conditions = append(conditions, map[string]string{"callback": callback})
+			fields["callback"] = callback
+		}
+	}
+
+	policyDocument := map[string]any{
+		"expiration": time.Now().Add(time.Duration(options.ExpiredInSec) * time.Second).UTC().Format(time.RFC3339Nano),
+		"conditions": conditions,
+	}
+
+	if bytes, err := json.Marshal(policyDocument); err != nil {
+		return "", nil, err
+	} else {
+		return base64.StdEncoding.EncodeToString(bytes), fields, nil
+	}
+}
+
+func postPolicyMaxSize(options PostPolicyOptions) int64 {
+	if options.MaxSize > 0 {
+		return options.MaxSize
+	}
+
+	return maxPostObjectSize
+}
@@ -0,0 +1,310 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+const (
+	s3PutPartSize = 16 * 1024 * 1024
+)
+
+type s3Backend struct {
+	config Config
+	client *minio.Client
+}
+
+func NewS3Backend(config Config) (Backend, error) {
+	endpoint, secure := parseS3Endpoint(config.Endpoint)
+
+	options := &minio.Options{
+		Creds:        credentials.NewStaticV4(config.AccessKeyId, config.AccessKeySecret, ""),
+		Secure:       secure,
+		Region:       config.Region,
+		BucketLookup: minio.BucketLookupAuto,
+	}
+
+	switch config.BucketLookup {
+	case BucketLookupPath:
+		options.BucketLookup = minio.BucketLookupPath
+	case BucketLookupDNS:
+		options.BucketLookup = minio.BucketLookupDNS
+	}
+
+	if client, err := minio.New(endpoint, options); err != nil {
+		return nil, err
+	} else {
+		return &s3Backend{config: config, client: client}, nil
+	}
+}
+
+// 大小未知时minio按最大对象大小计算分片，每次上传缓冲约537MiB，因此尽量传入实际大小，未知时固定分片大小
+func (b *s3Backend) Put(bucket string, key string, reader io.Reader, options PutOptions) error {
+	size := int64(-1)
+
+	if r, ok := reader.(interface{ Len() int }); ok {
+		size = int64(r.Len())
+	}
+
+	putOptions := minio.PutObjectOptions{ContentType: options.ContentType, PartSize: s3PutPartSize}
+
+	if options.ContentMD5 != "" {
+		if size < 0 {
+			return errors.New("指定内容MD5时必须能确定内容大小")
+		}
+
+		if _, err := b.core().PutObject(context.Background(), bucket, key, reader, size, options.ContentMD5, "", putOptions); minio.ToErrorResponse(err).Code == "BadDigest" {
+			return ErrBadDigest
+		} else {
+			return err
+		}
+	}
+
+	_, err := b.client.PutObject(context.Background(), bucket, key, reader, size, putOptions)
+
+	return err
+}
+
+func (b *s3Backend) PutFile(bucket string, key string, file string) error {
+	_, err := b.client.FPutObject(context.Background(), bucket, key, file, minio.PutObjectOptions{})
+
+	return err
+}
+
+func (b *s3Backend) Get(bucket string, key string) (io.ReadCloser, error) {
+	return b.client.GetObject(context.Background(), bucket, key, minio.GetObjectOptions{})
+}
+
+func (b *s3Backend) GetRange(bucket string, key string, offset int64, length int64) (io.ReadCloser, error) {
+	options := minio.GetObjectOptions{}
+
+	if err := options.SetRange(offset, offset+length-1); err != nil {
+		return nil, err
+	}
+
+	return b.client.GetObject(context.Background(), bucket, key, options)
+}
+
+func (b *s3Backend) Head(bucket string, key string) (*ObjectInfo, error) {
+	if objectInfo, err := b.client.StatObject(context.Background(), bucket, key, minio.StatObjectOptions{}); err != nil {
+		if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+			return nil, ErrObjectNotFound
+		}
+
+		return nil, err
+	} else {
+		return &ObjectInfo{
+			Key:          key,
+			Size:         objectInfo.Size,
+			ContentType:  objectInfo.ContentType,
+			ETag:         objectInfo.ETag,
+			LastModified: objectInfo.LastModified,
+		}, nil
+	}
+}
+
+func (b *s3Backend) Delete(bucket string, key string) error {
+	return b.client.RemoveObject(context.Background(), bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (b *s3Backend) Copy(bucket string, srcKey string, destKey string) error {
+	_, err := b.client.CopyObject(
+		context.Background(),
+		minio.CopyDestOptions{Bucket: bucket, Object: destKey},
+		minio.CopySrcOptions{Bucket: bucket, Object: srcKey},
+	)
+
+	return err
+}
+
+func (b *s3Backend) List(bucket string, prefix string, marker string, maxKeys int) (*ListResult, error) {
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	listResult := &ListResult{Objects: make([]ObjectInfo, 0)}
+
+	for objectInfo := range b.client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix, StartAfter: marker, Recursive: true}) {
+		if objectInfo.Err != nil {
+			return nil, objectInfo.Err
+		}
+
+		if len(listResult.Objects) == maxKeys {
+			listResult.IsTruncated = true
+			listResult.NextMarker = listResult.Objects[maxKeys-1].Key
+
+			break
+		}
+
+		listResult.Objects = append(listResult.Objects, ObjectInfo{
+			Key:          objectInfo.Key,
+			Size:         objectInfo.Size,
+			ContentType:  objectInfo.ContentType,
+			ETag:         objectInfo.ETag,
+			LastModified: objectInfo.LastModified,
+		})
+	}
+
+	return listResult, nil
+}
+
+// S3的预签名地址对Host进行了签名，因此不能像OSS那样替换为自定义域名
+func (b *s3Backend) SignURL(bucket string, key string, options SignOptions) (string, error) {
+	method := options.Method
+
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	params := url.Values{}
+
+	for k, v := range options.Params {
+		params.Set(k, v)
+	}
+
+	headers := http.Header{}
+
+	for k, v := range options.Headers {
+		headers.Set(k, v)
+	}
+
+	if u, err := b.client.PresignHeader(context.Background(), method, bucket, key, time.Duration(options.ExpiredInSec)*time.Second, params, headers); err != nil {
+		return "", err
+	} else {
+		return u.String(), nil
+	}
+}
+
+func (b *s3Backend) PostPolicyToken(bucket string, key string, options PostPolicyOptions) (*PostPolicyToken, error) {
+	policy := minio.NewPostPolicy()
+
+	if err := policy.SetBucket(bucket); err != nil {
+		return nil, err
+	} else if err := policy.SetKey(key); err != nil {
+		return nil, err
+	} else if err := policy.SetExpires(time.Now().UTC().Add(time.Duration(options.ExpiredInSec) * time.Second)); err != nil {
+		return nil, err
+	}
+
+	if options.MinSize > 0 || options.MaxSize > 0 {
+		if err := policy.SetContentLengthRange(options.MinSize, postPolicyMaxSize(options)); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.ContentType != "" {
+		if err := policy.SetContentType(options.ContentType); err != nil {
+			return nil, err
+		}
+	} else if options.ContentTypePrefix != "" {
+		if err := policy.SetContentTypeStartsWith(options.ContentTypePrefix); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.ContentDisposition != "" {
+		if err := policy.SetContentDisposition(options.ContentDisposition); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.SuccessActionStatus != "" {
+		if err := policy.SetSuccessStatusAction(options.SuccessActionStatus); err != nil {
+			return nil, err
+		}
+	}
+
+	for k, v := range options.Meta {
+		if err := policy.SetUserMetadata(k, v); err != nil {
+			return nil, err
+		}
+	}
+
+	if u, formData, err := b.client.PresignedPostPolicy(context.Background(), policy); err != nil {
+		return nil, err
+	} else {
+		return &PostPolicyToken{
+			UploadUrl:   u.String(),
+			AccessKeyId: b.config.AccessKeyId,
+			Policy:      formData["policy"],
+			Signature:   formData["x-amz-signature"],
+			Fields:      formData,
+		}, nil
+	}
+}
+
+func (b *s3Backend) InitiateMultipartUpload(bucket string, key string, options PutOptions) (string, error) {
+	return b.core().NewMultipartUpload(context.Background(), bucket, key, minio.PutObjectOptions{ContentType: options.ContentType})
+}
+
+func (b *s3Backend) UploadPart(bucket string, key string, uploadId string, partNumber int, reader io.Reader, size int64) (*Part, error) {
+	if part, err := b.core().PutObjectPart(context.Background(), bucket, key, uploadId, partNumber, reader, size, minio.PutObjectPartOptions{}); err != nil {
+		return nil, err
+	} else {
+		return &Part{PartNumber: part.PartNumber, ETag: part.ETag, Size: part.Size}, nil
+	}
+}
+
+func (b *s3Backend) ListParts(bucket string, key string, uploadId string) ([]Part, error) {
+	parts := make([]Part, 0)
+	partNumberMarker := 0
+
+	for {
+		result, err := b.core().ListObjectParts(context.Background(), bucket, key, uploadId, partNumberMarker, 1000)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, part := range result.ObjectParts {
+			parts = append(parts, Part{PartNumber: part.PartNumber, ETag: part.ETag, Size: part.Size})
+		}
+
+		if !result.IsTruncated {
+			break
+		}
+
+		partNumberMarker = result.NextPartNumberMarker
+	}
+
+	return parts, nil
+}
+
+func (b *s3Backend) CompleteMultipartUpload(bucket string, key string, uploadId string, parts []Part) error {
+	completeParts := make([]minio.CompletePart, len(parts))
+
+	for i, part := range parts {
+		completeParts[i] = minio.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag}
+	}
+
+	_, err := b.core().CompleteMultipartUpload(context.Background(), bucket, key, uploadId, completeParts, minio.PutObjectOptions{})
+
+	return err
+}
+
+func (b *s3Backend) AbortMultipartUpload(bucket string, key string, uploadId string) error {
+	return b.core().AbortMultipartUpload(context.Background(), bucket, key, uploadId)
+}
+
+func (b *s3Backend) core() minio.Core {
+	return minio.Core{Client: b.client}
+}
+
+func parseS3Endpoint(endpoint string) (string, bool) {
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		return u.Host, u.Scheme != "http"
+	}
+
+	return endpoint, true
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+// 需要本地MinIO实例，例如：
+// docker run -p 9000:9000 minio/minio server /data
+// S3_TEST_ENDPOINT=http://127.0.0.1:9000 S3_TEST_ACCESS_KEY_ID=minioadmin S3_TEST_ACCESS_KEY_SECRET=minioadmin S3_TEST_BUCKET=test go test ./storage/
+func Test_s3Backend(t *testing.T) {
+	endpoint := os.Getenv("S3_TEST_ENDPOINT")
+	bucket := os.Getenv("S3_TEST_BUCKET")
+
+	if endpoint == "" || bucket == "" {
+		t.Skip("S3_TEST_ENDPOINT or S3_TEST_BUCKET not set")
+	}
+
+	backend, err := NewS3Backend(Config{
+		Provider:        ProviderS3,
+		Endpoint:        endpoint,
+		AccessKeyId:     os.Getenv("S3_TEST_ACCESS_KEY_ID"),
+		AccessKeySecret: os.Getenv("S3_TEST_ACCESS_KEY_SECRET"),
+		Region:          "us-east-1",
+		BucketLookup:    BucketLookupPath,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := "file-service-test/a.txt"
+
+	if err := backend.Put(bucket, key, strings.NewReader("hello"), PutOptions{ContentType: "text/plain"}); err != nil {
+		t.Fatal(err)
+	}
+
+	defer backend.Delete(bucket, key)
+
+	md5Key := "file-service-test/md5.txt"
+
+	defer backend.Delete(bucket, md5Key)
+
+	if err := backend.Put(bucket, md5Key, strings.NewReader("hello"), PutOptions{ContentMD5: "XUFAKrxLKna5cZ2REBfFkg=="}); err != nil {
+		t.Errorf("expected matching md5 accepted, got %v", err)
+	} else if err := backend.Put(bucket, md5Key, strings.NewReader("hellO"), PutOptions{ContentMD5: "XUFAKrxLKna5cZ2REBfFkg=="}); err != ErrBadDigest {
+		t.Errorf("expected ErrBadDigest, got %v", err)
+	} else if err := backend.Put(bucket, md5Key, io.MultiReader(strings.NewReader("hello")), PutOptions{ContentMD5: "XUFAKrxLKna5cZ2REBfFkg=="}); err == nil {
+		t.Error("expected md5 with unknown size rejected")
+	}
+
+	if objectInfo, err := backend.Head(bucket, key); err != nil {
+		t.Fatal(err)
+	} else if objectInfo.Size != 5 {
+		t.Errorf("unexpected object info: %+v", objectInfo)
+	}
+
+	if reader, err := backend.GetRange(bucket, key, 1, 3); err != nil {
+		t.Fatal(err)
+	} else {
+		bytes, _ := io.ReadAll(reader)
+
+		reader.Close()
+
+		if string(bytes) != "ell" {
+			t.Errorf("unexpected range content: %s", bytes)
+		}
+	}
+
+	if _, err := backend.Head(bucket, "file-service-test/missing.txt"); err != ErrObjectNotFound {
+		t.Errorf("expected ErrObjectNotFound, got %v", err)
+	}
+
+	if signedURL, err := backend.SignURL(bucket, key, SignOptions{ExpiredInSec: 60}); err != nil {
+		t.Fatal(err)
+	} else if resp, err := http.Get(signedURL); err != nil {
+		t.Fatal(err)
+	} else {
+		bytes, _ := io.ReadAll(resp.Body)
+
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK || string(bytes) != "hello" {
+			t.Errorf("unexpected presigned response: %d %s", resp.StatusCode, bytes)
+		}
+	}
+
+	putKey := "file-service-test/d.png"
+
+	if putURL, err := backend.SignURL(bucket, putKey, SignOptions{Method: http.MethodPut, ExpiredInSec: 60, Headers: map[string]string{"Content-Type": "image/png"}}); err != nil {
+		t.Fatal(err)
+	} else {
+		defer backend.Delete(bucket, putKey)
+
+		for contentType, statusCode := range map[string]int{"text/html": http.StatusForbidden, "image/png": http.StatusOK} {
+			req, _ := http.NewRequest(http.MethodPut, putURL, strings.NewReader("png"))
+
+			req.Header.Set("Content-Type", contentType)
+
+			if resp, err := http.DefaultClient.Do(req); err != nil {
+				t.Fatal(err)
+			} else if resp.Body.Close(); resp.StatusCode != statusCode {
+				t.Errorf("unexpected presigned put response for %s: %d", contentType, resp.StatusCode)
+			}
+		}
+	}
+
+	if policyToken, err := backend.PostPolicyToken(bucket, key, PostPolicyOptions{ExpiredInSec: 60, MaxSize: 1024, ContentType: "image/png", Meta: map[string]string{"owner": "alice"}}); err != nil {
+		t.Fatal(err)
+	} else if policyToken.Policy == "" || policyToken.Fields["x-amz-credential"] == "" || policyToken.Fields["Content-Type"] != "image/png" || policyToken.Fields["x-amz-meta-owner"] != "alice" {
+		t.Errorf("unexpected post policy: %+v", policyToken)
+	}
+
+	multipartKey := "file-service-test/b.bin"
+
+	uploadId, err := backend.InitiateMultipartUpload(bucket, multipartKey, PutOptions{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := backend.UploadPart(bucket, multipartKey, uploadId, 1, strings.NewReader(strings.Repeat("a", 5*1024*1024)), 5*1024*1024); err != nil {
+		t.Fatal(err)
+	} else if _, err := backend.UploadPart(bucket, multipartKey, uploadId, 2, strings.NewReader("tail"), 4); err != nil {
+		t.Fatal(err)
+	}
+
+	if parts, err := backend.ListParts(bucket, multipartKey, uploadId); err != nil {
+		t.Fatal(err)
+	} else if len(parts) != 2 {
+		t.Fatalf("unexpected parts: %+v", parts)
+	} else if err := backend.CompleteMultipartUpload(bucket, multipartKey, uploadId, parts); err != nil {
+		t.Fatal(err)
+	}
+
+	defer backend.Delete(bucket, multipartKey)
+
+	presignedKey := "file-service-test/c.bin"
+
+	if uploadId, err := backend.InitiateMultipartUpload(bucket, presignedKey, PutOptions{}); err != nil {
+		t.Fatal(err)
+	} else if partURL, err := backend.SignURL(bucket, presignedKey, SignOptions{Method: http.MethodPut, ExpiredInSec: 60, Params: map[string]string{"uploadId": uploadId, "partNumber": "1"}}); err != nil {
+		t.Fatal(err)
+	} else {
+		defer backend.AbortMultipartUpload(bucket, presignedKey, uploadId)
+
+		req, _ := http.NewRequest(http.MethodPut, partURL, strings.NewReader("part"))
+
+		if resp, err := http.DefaultClient.Do(req); err != nil {
+			t.Fatal(err)
+		} else {
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("unexpected presigned part response: %d", resp.StatusCode)
+			} else if parts, err := backend.ListParts(bucket, presignedKey, uploadId); err != nil || len(parts) != 1 || parts[0].Size != 4 {
+				t.Errorf("unexpected parts after presigned upload: %+v %v", parts, err)
+			}
+		}
+	}
+
+	if objectInfo, err := backend.Head(bucket, multipartKey); err != nil {
+		t.Fatal(err)
+	} else if objectInfo.Size != 5*1024*1024+4 {
+		t.Errorf("unexpected multipart object info: %+v", objectInfo)
+	}
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func Test_aliyunRpcSignature(t *testing.T) {
+	params := url.Values{
+		"AccessKeyId":      {"testid"},
+		"Action":           {"DescribeRegions"},
+		"Format":           {"XML"},
+		"SignatureMethod":  {"HMAC-SHA1"},
+		"SignatureNonce":   {"3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf"},
+		"SignatureVersion": {"1.0"},
+		"Timestamp":        {"2016-02-23T12:46:24Z"},
+		"Version":          {"2014-05-26"},
+	}
+
+	if signature := aliyunRpcSignature(http.MethodGet, params, "testsecret"); signature != "OLeaidS1JvxuMvnyHOwuJ+uX5qY=" {
+		t.Errorf("unexpected signature: %s", signature)
+	}
+}
+
+func Test_aliyunStsProvider_AssumeRole(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		if r.Form.Get("Signature") != aliyunRpcSignature(http.MethodPost, r.Form, "sk") {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"Code": "SignatureDoesNotMatch", "Message": "Specified signature is not matched with our calculation."})
+
+			return
+		}
+
+		if r.Form.Get("Action") != "AssumeRole" || r.Form.Get("RoleArn") != "acs:ram::1:role/upload" || r.Form.Get("DurationSeconds") != "900" || !strings.Contains(r.Form.Get("Policy"), "test/images/*") {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"Code": "InvalidParameter", "Message": r.Form.Encode()})
+
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"RequestId": "1",
+			"Credentials": map[string]string{
+				"AccessKeyId":     "STS.tmp",
+				"AccessKeySecret": "tmp-secret",
+				"SecurityToken":   "token",
+				"Expiration":      "2026-01-01T00:15:00Z",
+			},
+		})
+	}))
+
+	defer server.Close()
+
+	provider, err := NewCredentialProvider(Config{Provider: ProviderOss, AccessKeyId: "ak", AccessKeySecret: "sk", RoleArn: "acs:ram::1:role/upload", StsEndpoint: server.URL})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := AssumeRoleOptions{SessionName: "file-service", Policy: `{"Resource":["acs:oss:*:*:test/images/*"]}`, DurationSeconds: 900}
+
+	if credentials, err := provider.AssumeRole(options); err != nil {
+		t.Fatal(err)
+	} else if credentials.AccessKeyId != "STS.tmp" || credentials.SecurityToken != "token" || credentials.Expiration.Unix() != 1767226500 {
+		t.Errorf("unexpected credentials: %+v", credentials)
+	}
+
+	provider, _ = NewCredentialProvider(Config{Provider: ProviderOss, AccessKeyId: "ak", AccessKeySecret: "wrong", RoleArn: "acs:ram::1:role/upload", StsEndpoint: server.URL})
+
+	if _, err := provider.AssumeRole(options); err == nil || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Errorf("expected signature error, got %v", err)
+	}
+
+	if _, err := NewCredentialProvider(Config{Provider: ProviderLocal}); err != ErrCredentialsNotSupported {
+		t.Errorf("expected ErrCredentialsNotSupported, got %v", err)
+	}
+}